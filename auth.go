@@ -2,18 +2,22 @@ package gosalla
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 )
 
 const (
-	// OAuth endpoints
-	authorizationURL = "https://accounts.salla.sa/oauth2/auth"
-	tokenURL         = "https://accounts.salla.sa/oauth2/token"
+	// DefaultAuthURL is Salla's OAuth authorization endpoint
+	DefaultAuthURL = "https://accounts.salla.sa/oauth2/auth"
+	
+	// DefaultTokenURL is Salla's OAuth token endpoint
+	DefaultTokenURL = "https://accounts.salla.sa/oauth2/token"
 )
 
 // OAuthConfig holds the OAuth 2.0 configuration
@@ -21,7 +25,24 @@ type OAuthConfig struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURI  string
-	Scopes       []string
+	Scopes       []Scope
+	
+	// AuthURL and TokenURL override Salla's OAuth endpoints, e.g. to point
+	// the flow at a proxy or a test server. Empty values use the defaults.
+	AuthURL  string
+	TokenURL string
+	
+	// UserInfoURL overrides Salla's user info endpoint. An empty value
+	// uses DefaultUserInfoURL.
+	UserInfoURL string
+	
+	// HTTPClient is used for token requests. If nil, a client with a
+	// 30 second timeout is used.
+	HTTPClient *http.Client
+	
+	// Logger, if set, receives a record of every token and user info
+	// request, with secrets redacted
+	Logger *slog.Logger
 }
 
 // TokenResponse represents the response from the token endpoint
@@ -39,6 +60,10 @@ type Token struct {
 	RefreshToken string
 	TokenType    string
 	Expiry       time.Time
+	
+	// Scopes are the scopes granted to the token. They are empty if the
+	// token endpoint did not report them.
+	Scopes []Scope
 }
 
 // Valid checks if the token is still valid (not expired)
@@ -46,67 +71,113 @@ func (t *Token) Valid() bool {
 	return t.AccessToken != "" && time.Now().Before(t.Expiry)
 }
 
-// GetAuthorizationURL generates the OAuth authorization URL
-func (c *OAuthConfig) GetAuthorizationURL(state string) string {
+// GetAuthorizationURL generates the OAuth authorization URL. Pass
+// S256ChallengeOption to use PKCE.
+func (c *OAuthConfig) GetAuthorizationURL(state string, opts ...AuthCodeOption) string {
 	params := url.Values{}
 	params.Add("client_id", c.ClientID)
 	params.Add("redirect_uri", c.RedirectURI)
 	params.Add("response_type", "code")
 	params.Add("state", state)
+	params.Add("scope", c.scope())
 	
-	if len(c.Scopes) > 0 {
-		scopes := ""
-		for i, scope := range c.Scopes {
-			if i > 0 {
-				scopes += " "
-			}
-			scopes += scope
-		}
-		params.Add("scope", scopes)
-	} else {
-		params.Add("scope", "offline_access")
+	for _, opt := range opts {
+		opt(params)
 	}
 
-	return fmt.Sprintf("%s?%s", authorizationURL, params.Encode())
+	return fmt.Sprintf("%s?%s", c.authURL(), params.Encode())
 }
 
-// ExchangeCode exchanges an authorization code for an access token
-func (c *OAuthConfig) ExchangeCode(code string) (*Token, error) {
+// ExchangeCode exchanges an authorization code for an access token. Pass
+// VerifierOption if the authorization URL carried a PKCE challenge.
+func (c *OAuthConfig) ExchangeCode(ctx context.Context, code string, opts ...AuthCodeOption) (*Token, error) {
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
 	data.Set("client_id", c.ClientID)
-	data.Set("client_secret", c.ClientSecret)
+	if c.ClientSecret != "" {
+		data.Set("client_secret", c.ClientSecret)
+	}
 	data.Set("code", code)
 	data.Set("redirect_uri", c.RedirectURI)
-	data.Set("scope", "offline_access")
+	data.Set("scope", c.scope())
+	
+	for _, opt := range opts {
+		opt(data)
+	}
 
-	return c.requestToken(data)
+	return c.requestToken(ctx, data)
 }
 
 // RefreshToken refreshes an expired access token using the refresh token
-func (c *OAuthConfig) RefreshToken(refreshToken string) (*Token, error) {
+func (c *OAuthConfig) RefreshToken(ctx context.Context, refreshToken string) (*Token, error) {
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("client_id", c.ClientID)
-	data.Set("client_secret", c.ClientSecret)
+	if c.ClientSecret != "" {
+		data.Set("client_secret", c.ClientSecret)
+	}
 	data.Set("refresh_token", refreshToken)
 
-	return c.requestToken(data)
+	return c.requestToken(ctx, data)
+}
+
+// scope returns the space separated scopes to request
+func (c *OAuthConfig) scope() string {
+	if len(c.Scopes) == 0 {
+		return "offline_access"
+	}
+	return joinScopes(c.Scopes)
+}
+
+// authURL returns the authorization endpoint
+func (c *OAuthConfig) authURL() string {
+	if c.AuthURL != "" {
+		return c.AuthURL
+	}
+	return DefaultAuthURL
+}
+
+// tokenURL returns the token endpoint
+func (c *OAuthConfig) tokenURL() string {
+	if c.TokenURL != "" {
+		return c.TokenURL
+	}
+	return DefaultTokenURL
+}
+
+// defaultOAuthClient is used for token requests when no HTTPClient is configured
+var defaultOAuthClient = &http.Client{Timeout: 30 * time.Second}
+
+// httpClient returns the HTTP client used for token requests
+func (c *OAuthConfig) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultOAuthClient
+}
+
+// send sends req over the HTTP client for token requests and logs it
+func (c *OAuthConfig) send(req *Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.httpClient().Do(req.Request)
+	if c.Logger != nil {
+		logRoundTrip(req.Context(), c.Logger, req, resp, err, 1, time.Since(start))
+	}
+	return resp, err
 }
 
 // requestToken makes a request to the token endpoint
-func (c *OAuthConfig) requestToken(data url.Values) (*Token, error) {
-	req, err := http.NewRequest("POST", tokenURL, bytes.NewBufferString(data.Encode()))
+func (c *OAuthConfig) requestToken(ctx context.Context, data url.Values) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.tokenURL(), bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.send(&Request{Request: req, Operation: "oauth." + data.Get("grant_type")})
 	if err != nil {
-		return nil, fmt.Errorf("failed to request token: %w", err)
+		return nil, fmt.Errorf("failed to request token: %w", &TransportError{Err: err})
 	}
 	defer resp.Body.Close()
 
@@ -116,7 +187,7 @@ func (c *OAuthConfig) requestToken(data url.Values) (*Token, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, parseOAuthError(resp.StatusCode, body)
 	}
 
 	var tokenResp TokenResponse
@@ -129,6 +200,7 @@ func (c *OAuthConfig) requestToken(data url.Values) (*Token, error) {
 		RefreshToken: tokenResp.RefreshToken,
 		TokenType:    tokenResp.TokenType,
 		Expiry:       time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		Scopes:       parseScopes(tokenResp.Scope),
 	}
 
 	return token, nil
@@ -0,0 +1,329 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer starts a stub token endpoint that records the last form it received
+func newTokenServer(t *testing.T, form *url.Values) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse token request: %v", err)
+		}
+		*form = r.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"access_token": "access-` + r.PostForm.Get("grant_type") + `",
+			"token_type": "bearer",
+			"expires_in": 3600,
+			"refresh_token": "refresh-2",
+			"scope": "offline_access categories.read_write"
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetAuthorizationURL(t *testing.T) {
+	config := &OAuthConfig{
+		ClientID:    "client",
+		RedirectURI: "https://app.example.com/callback",
+		AuthURL:     "https://auth.example.com/authorize",
+	}
+
+	authURL, err := url.Parse(config.GetAuthorizationURL("xyz"))
+	if err != nil {
+		t.Fatalf("Invalid authorization URL: %v", err)
+	}
+
+	if !strings.HasPrefix(authURL.String(), "https://auth.example.com/authorize?") {
+		t.Errorf("Expected configured endpoint, got %s", authURL)
+	}
+
+	query := authURL.Query()
+	if query.Get("state") != "xyz" || query.Get("client_id") != "client" || query.Get("response_type") != "code" {
+		t.Errorf("Unexpected query: %v", query)
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	var form url.Values
+	server := newTokenServer(t, &form)
+
+	config := &OAuthConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURI:  "https://app.example.com/callback",
+		TokenURL:     server.URL,
+		HTTPClient:   server.Client(),
+	}
+
+	token, err := config.ExchangeCode(context.Background(), "code-1")
+	if err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+
+	if form.Get("grant_type") != "authorization_code" || form.Get("code") != "code-1" || form.Get("client_secret") != "secret" {
+		t.Errorf("Unexpected token request: %v", form)
+	}
+
+	if token.AccessToken != "access-authorization_code" || token.RefreshToken != "refresh-2" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	if len(token.Scopes) != 2 || token.Scopes[1] != ScopeCategoriesReadWrite {
+		t.Errorf("Expected granted scopes from the response, got %v", token.Scopes)
+	}
+
+	if token.Expiry.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("Expected token to expire in an hour, got %v", token.Expiry)
+	}
+}
+
+func TestClientRefreshesThroughTokenEndpoint(t *testing.T) {
+	var form url.Values
+	tokenServer := newTokenServer(t, &form)
+
+	var auth string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer apiServer.Close()
+
+	config := &OAuthConfig{ClientID: "client", TokenURL: tokenServer.URL}
+	client := NewClient(WithToken(config, &Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh-1",
+		Expiry:       time.Now().Add(-time.Minute),
+	}), WithBaseURL(apiServer.URL))
+
+	if _, err := client.Categories.Delete(context.Background(), 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "refresh-1" {
+		t.Errorf("Unexpected refresh request: %v", form)
+	}
+
+	if auth != "Bearer access-refresh_token" {
+		t.Errorf("Expected refreshed token on the request, got %q", auth)
+	}
+}
+
+func TestRefreshTokenOAuthError(t *testing.T) {
+	status, body := http.StatusBadRequest, `{"error": "invalid_grant", "error_description": "The refresh token is invalid."}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := &OAuthConfig{TokenURL: server.URL}
+
+	_, err := config.RefreshToken(context.Background(), "revoked")
+
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) {
+		t.Fatalf("Expected OAuthError, got %v", err)
+	}
+
+	if oauthErr.Code != "invalid_grant" || oauthErr.Description != "The refresh token is invalid." {
+		t.Errorf("Unexpected OAuth error: %+v", oauthErr)
+	}
+
+	if !IsReauthorizationRequired(err) {
+		t.Error("Expected invalid_grant to require reauthorization")
+	}
+
+	// A gateway failure is transient and must not ask for reauthorization
+	status, body = http.StatusBadGateway, `<html>Bad Gateway</html>`
+
+	_, err = config.RefreshToken(context.Background(), "refresh")
+	if !errors.As(err, &oauthErr) || oauthErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Expected OAuthError with status 502, got %v", err)
+	}
+
+	if IsReauthorizationRequired(err) {
+		t.Error("Expected a 502 not to require reauthorization")
+	}
+}
+
+func TestClientReauthorizationRequired(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithToken(&OAuthConfig{TokenURL: server.URL}, &Token{
+		AccessToken:  "expired",
+		RefreshToken: "revoked",
+		Expiry:       time.Now().Add(-time.Hour),
+	}))
+
+	var hookErr error
+	var hookCalls int
+	client.OnReauthorizationRequired(func(err error) {
+		hookErr = err
+		hookCalls++
+	})
+
+	err := client.RefreshTokenIfNeeded(context.Background())
+	if !errors.Is(err, ErrReauthorizationRequired) {
+		t.Fatalf("Expected ErrReauthorizationRequired, got %v", err)
+	}
+
+	if !errors.Is(hookErr, ErrReauthorizationRequired) {
+		t.Errorf("Expected hook to receive the error, got %v", hookErr)
+	}
+
+	// The failure sticks without asking the token endpoint again
+	err = client.RefreshTokenIfNeeded(context.Background())
+	if !errors.Is(err, ErrReauthorizationRequired) {
+		t.Fatalf("Expected ErrReauthorizationRequired again, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 || hookCalls != 1 {
+		t.Errorf("Expected 1 token request and 1 hook call, got %d and %d", n, hookCalls)
+	}
+
+	// A new token clears it
+	client.SetToken(&Token{AccessToken: "reauthorized", Expiry: time.Now().Add(time.Hour)})
+	if err := client.RefreshTokenIfNeeded(context.Background()); err != nil {
+		t.Errorf("Expected the new token to be usable, got %v", err)
+	}
+}
+
+func TestClientReauthorizationRequiredNearExpiry(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant"}`))
+	}))
+	defer tokenServer.Close()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+
+	// The token still works, but is within the refresh margin
+	client := NewClient(WithToken(&OAuthConfig{TokenURL: tokenServer.URL}, &Token{
+		AccessToken:  "expiring",
+		RefreshToken: "revoked",
+		Expiry:       time.Now().Add(2 * time.Minute),
+	}), WithBaseURL(server.URL))
+
+	for i := 0; i < 2; i++ {
+		req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+		if _, err := client.do(req, nil); !errors.Is(err, ErrReauthorizationRequired) {
+			t.Errorf("Expected ErrReauthorizationRequired, got %v", err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("Expected no API requests, got %d", n)
+	}
+}
+
+func TestExchangeCodePKCE(t *testing.T) {
+	var form url.Values
+	server := newTokenServer(t, &form)
+
+	config := &OAuthConfig{
+		ClientID: "cli",
+		Scopes:   []Scope{ScopeOfflineAccess, ScopeProductsRead},
+		AuthURL:  "https://auth.example.com/authorize",
+		TokenURL: server.URL,
+	}
+
+	verifier, err := GenerateVerifier()
+	if err != nil {
+		t.Fatalf("Failed to generate verifier: %v", err)
+	}
+
+	authURL, _ := url.Parse(config.GetAuthorizationURL("xyz", S256ChallengeOption(verifier)))
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != "S256" || !VerifyS256Challenge(verifier, query.Get("code_challenge")) {
+		t.Errorf("Expected S256 challenge for the verifier, got %v", query)
+	}
+
+	if _, err := config.ExchangeCode(context.Background(), "code-1", VerifierOption(verifier)); err != nil {
+		t.Fatalf("Failed to exchange code: %v", err)
+	}
+
+	if form.Get("code_verifier") != verifier {
+		t.Errorf("Expected code verifier in exchange, got %q", form.Get("code_verifier"))
+	}
+
+	if form.Get("scope") != "offline_access products.read" {
+		t.Errorf("Expected configured scopes in exchange, got %q", form.Get("scope"))
+	}
+
+	if _, ok := form["client_secret"]; ok {
+		t.Error("Expected no client secret for a public client")
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{
+			"status": 200,
+			"success": true,
+			"data": {
+				"id": 7,
+				"name": "Sara",
+				"email": "sara@example.com",
+				"mobile": "+966500000000",
+				"role": "user",
+				"merchant": {"id": 1234, "username": "dev-store", "name": "Dev Store", "plan": "pro", "status": "active", "domain": "https://dev-store.example.com"},
+				"context": {"app": 99, "scope": "offline_access products.read", "exp": 1700000000}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	config := &OAuthConfig{UserInfoURL: server.URL}
+	token := &Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}
+
+	info, err := config.UserInfo(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Failed to get user info: %v", err)
+	}
+
+	if info.Merchant.ID != 1234 || info.Merchant.Plan != "pro" || info.Email != "sara@example.com" {
+		t.Errorf("Unexpected user info: %+v", info)
+	}
+
+	if len(info.Context.Scopes) != 2 || info.Context.ExpiresAt.Unix() != 1700000000 {
+		t.Errorf("Unexpected token context: %+v", info.Context)
+	}
+
+	me, _, err := NewClient(WithToken(config, token)).Me(context.Background())
+	if err != nil || me.Merchant.ID != 1234 {
+		t.Errorf("Expected merchant 1234 from Me, got %+v (%v)", me, err)
+	}
+
+	if _, err := config.UserInfo(context.Background(), &Token{AccessToken: "revoked"}); !IsUnauthorizedError(err) {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
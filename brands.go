@@ -1,7 +1,9 @@
 package gosalla
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -59,86 +61,108 @@ type UpdateBrandRequest struct {
 }
 
 // List retrieves all brands with optional pagination
-func (s *BrandsService) List(opts *ListOptions) ([]Brand, *Pagination, error) {
-	path := "/brands"
-	
-	// Add query parameters
-	if opts != nil {
-		path += fmt.Sprintf("?page=%d&per_page=%d", opts.Page, opts.PerPage)
+func (s *BrandsService) List(ctx context.Context, opts *ListOptions, reqOpts ...RequestOption) ([]Brand, *Response, error) {
+	path, err := addOptions("/brands", opts)
+	if err != nil {
+		return nil, nil, err
 	}
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
 		return nil, nil, err
 	}
+	req.scope = ScopeBrandsRead
+	req.Operation = "brands.list"
 	
-	var resp BrandsListResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, nil, err
+	var result BrandsListResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return resp.Data, resp.Pagination, nil
+	return result.Data, resp, nil
+}
+
+// All returns a Pager over every brand, starting at the page in opts
+func (s *BrandsService) All(ctx context.Context, opts *ListOptions, reqOpts ...RequestOption) *Pager[Brand] {
+	return newPager(ctx, opts, func(ctx context.Context, opts *ListOptions) ([]Brand, *Response, error) {
+		return s.List(ctx, opts, reqOpts...)
+	})
 }
 
 // Get retrieves a brand by ID
-func (s *BrandsService) Get(id int) (*Brand, error) {
+func (s *BrandsService) Get(ctx context.Context, id int, reqOpts ...RequestOption) (*Brand, *Response, error) {
 	path := fmt.Sprintf("/brands/%d", id)
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeBrandsRead
+	req.Operation = "brands.get"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp BrandResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result BrandResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Create creates a new brand
-func (s *BrandsService) Create(brand *CreateBrandRequest) (*Brand, error) {
+func (s *BrandsService) Create(ctx context.Context, brand *CreateBrandRequest, reqOpts ...RequestOption) (*Brand, *Response, error) {
 	path := "/brands"
 	
-	req, err := s.client.newRequest("POST", path, brand)
+	req, err := s.client.newRequest(ctx, "POST", path, brand, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeBrandsReadWrite
+	req.Operation = "brands.create"
 	
-	var resp BrandResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result BrandResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Update updates an existing brand
-func (s *BrandsService) Update(id int, brand *UpdateBrandRequest) (*Brand, error) {
+func (s *BrandsService) Update(ctx context.Context, id int, brand *UpdateBrandRequest, reqOpts ...RequestOption) (*Brand, *Response, error) {
 	path := fmt.Sprintf("/brands/%d", id)
 	
-	req, err := s.client.newRequest("PUT", path, brand)
+	req, err := s.client.newRequest(ctx, "PUT", path, brand, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeBrandsReadWrite
+	req.Operation = "brands.update"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp BrandResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result BrandResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Delete deletes a brand
-func (s *BrandsService) Delete(id int) error {
+func (s *BrandsService) Delete(ctx context.Context, id int, reqOpts ...RequestOption) (*Response, error) {
 	path := fmt.Sprintf("/brands/%d", id)
 	
-	req, err := s.client.newRequest("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, "DELETE", path, nil, reqOpts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	req.scope = ScopeBrandsReadWrite
+	req.Operation = "brands.delete"
+	req.ResourceID = strconv.Itoa(id)
 	
 	return s.client.do(req, nil)
 }
@@ -0,0 +1,279 @@
+package gosalla
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBulkConcurrency is the number of pages fetched at the same time
+// when BulkOptions.Concurrency is zero
+const DefaultBulkConcurrency = 4
+
+// BulkOptions configures concurrent page fetching for bulk exports.
+// Requests still pass through the client's rate limiter, so higher
+// concurrency never exceeds the merchant's quota.
+type BulkOptions struct {
+	// Concurrency is the number of pages fetched at the same time
+	Concurrency int
+
+	// OnProgress, if set, is called after every fetched page. Calls are
+	// never concurrent.
+	OnProgress func(Progress)
+}
+
+// Progress reports how far a bulk fetch has come
+type Progress struct {
+	Pages      int // pages fetched so far
+	TotalPages int // pages to fetch in total
+	Items      int // items fetched so far
+	TotalItems int // items Salla reported for the whole list
+}
+
+// PageResult is a page delivered by Pager.Stream
+type PageResult[T any] struct {
+	Page  int
+	Items []T
+	Err   error
+}
+
+// FetchAll fetches every remaining page, several at a time after the first
+// page has revealed the page count, and returns the items in order. If a
+// page fails, FetchAll returns the items of the pages before it together
+// with the error, and Checkpoint points at the failed page.
+func (p *Pager[T]) FetchAll(opts *BulkOptions) ([]T, error) {
+	start := p.next.Page
+	if len(p.buf) > 0 {
+		start = p.current.Page
+	}
+	delivered := p.fetched - len(p.buf)
+
+	pages := make(map[int][]T)
+	err := p.fetchConcurrently(opts, func(result PageResult[T]) bool {
+		if result.Err == nil {
+			pages[result.Page] = result.Items
+		}
+		return true
+	})
+
+	// Keep the pages before the first missing one, so the result is a
+	// clean prefix that can be resumed from
+	var all []T
+	next := start
+	for ; ; next++ {
+		items, ok := pages[next]
+		if !ok {
+			break
+		}
+		all = append(all, items...)
+	}
+
+	if p.maxItems > 0 && len(all) > p.maxItems-delivered {
+		all = all[:p.maxItems-delivered]
+	}
+
+	// Pages after a failed one are dropped, so only the returned items
+	// count against the limit
+	p.fetched = delivered + len(all)
+
+	if err != nil {
+		p.more, p.buf, p.truncated = true, nil, false
+		p.next.Page = next
+		return all, err
+	}
+
+	return all, nil
+}
+
+// Stream fetches every remaining page, several at a time, and delivers
+// each page as soon as it arrives, so pages may be out of order. The
+// channel is closed when all pages have been delivered or after a page
+// failed with an error. Cancel the pager's context to stop early.
+func (p *Pager[T]) Stream(opts *BulkOptions) <-chan PageResult[T] {
+	results := make(chan PageResult[T])
+
+	go func() {
+		defer close(results)
+		p.fetchConcurrently(opts, func(result PageResult[T]) bool {
+			select {
+			case results <- result:
+				return result.Err == nil
+			case <-p.ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return results
+}
+
+// fetchConcurrently fetches the remaining pages with bounded concurrency
+// and passes each one to deliver, which returns false to stop. Calls to
+// deliver are never concurrent. It returns the first error.
+func (p *Pager[T]) fetchConcurrently(opts *BulkOptions, deliver func(PageResult[T]) bool) error {
+	concurrency := DefaultBulkConcurrency
+	var onProgress func(Progress)
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		onProgress = opts.OnProgress
+	}
+
+	// The first page reveals how many pages there are
+	first := p.current.Page
+	if len(p.buf) == 0 {
+		if !p.more {
+			return nil
+		}
+		first = p.next.Page
+		if err := p.fill(); err != nil {
+			deliver(PageResult[T]{Page: first, Err: err})
+			return err
+		}
+	}
+
+	progress := Progress{TotalPages: 1}
+	if pagination := p.resp.Pagination; pagination != nil {
+		progress.TotalItems = pagination.Total
+	}
+
+	firstItems := p.buf
+	p.buf = nil
+
+	// The current page may have been partly consumed, so its full length
+	// is the page size
+	perPage := p.pageSize
+	if pagination := p.resp.Pagination; pagination != nil && pagination.PerPage > 0 {
+		perPage = pagination.PerPage
+	}
+
+	from, to := p.next.Page, p.next.Page-1
+	if p.more && p.next.Cursor == "" {
+		to = p.lastPage(perPage)
+		if to >= from {
+			progress.TotalPages += to - from + 1
+		}
+	}
+
+	// Only the last page can hold more items than the limit allows
+	lastPageCap := -1
+	if p.maxItems > 0 {
+		lastPageCap = p.maxItems - p.fetched - (to-from)*perPage
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	stopped := false
+	var firstErr error
+
+	// Pages read by fill are counted in p.fetched already; those fetched
+	// by the workers below are counted as they are delivered
+	report := func(result PageResult[T], count bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return
+		}
+
+		if result.Err == nil {
+			if count {
+				p.fetched += len(result.Items)
+			}
+			progress.Pages++
+			progress.Items += len(result.Items)
+			if onProgress != nil {
+				onProgress(progress)
+			}
+		} else if firstErr == nil {
+			firstErr = result.Err
+		}
+
+		if !deliver(result) || result.Err != nil {
+			stopped = true
+			cancel()
+		}
+	}
+
+	report(PageResult[T]{Page: first, Items: firstItems}, false)
+
+	// Cursors are only known one page at a time, so they are followed in turn
+	if p.next.Cursor != "" {
+		for page := first + 1; p.more; page++ {
+			mu.Lock()
+			done := stopped
+			mu.Unlock()
+			if done {
+				break
+			}
+
+			err := p.fill()
+			report(PageResult[T]{Page: page, Items: p.buf, Err: err}, false)
+			p.buf = nil
+			if err != nil {
+				return err
+			}
+		}
+		p.more = false
+		return nil
+	}
+
+	p.more = false
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range jobs {
+				opts := p.next
+				opts.Page = page
+				items, _, err := p.fetch(ctx, &opts)
+				if page == to && lastPageCap >= 0 && len(items) > lastPageCap {
+					items = items[:lastPageCap]
+				}
+				report(PageResult[T]{Page: page, Items: items, Err: err}, true)
+			}
+		}()
+	}
+
+dispatch:
+	for page := from; page <= to; page++ {
+		mu.Lock()
+		done := stopped
+		mu.Unlock()
+		if done {
+			break
+		}
+
+		select {
+		case jobs <- page:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = p.ctx.Err()
+	}
+	return firstErr
+}
+
+// lastPage returns the last page to fetch, honoring the item limit.
+// perPage is the size of a full page.
+func (p *Pager[T]) lastPage(perPage int) int {
+	last := p.resp.Pagination.LastPage
+	if p.maxItems > 0 && perPage > 0 {
+		remaining := p.maxItems - p.fetched
+		if pages := p.next.Page + (remaining+perPage-1)/perPage - 1; pages < last {
+			last = pages
+		}
+	}
+	return last
+}
@@ -0,0 +1,196 @@
+package gosalla
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errMissingCode is returned when the callback carries neither a code nor an error
+var errMissingCode = errors.New("authorization code missing from callback")
+
+// errStateNotIssued is returned when a valid state arrives from a browser it
+// was not issued to, e.g. in a callback URL crafted by someone else
+var errStateNotIssued = fmt.Errorf("state was not issued to this browser: %w", ErrInvalidState)
+
+// stateCookie holds the state of the authorization a browser started
+const stateCookie = "salla_oauth_state"
+
+// OAuthCallbackHandler handles the redirect back from Salla's authorization
+// page. It verifies the state, exchanges the code for a token, hands the
+// token to the app and redirects the merchant. Errors passed back by Salla,
+// such as a merchant declining access, are rendered as well.
+type OAuthCallbackHandler struct {
+	config  *OAuthConfig
+	states  *StateSigner
+	onToken func(ctx context.Context, token *Token) error
+
+	// RedirectURL is where the merchant is sent after authorizing when the
+	// state carries no return path. Defaults to "/".
+	RedirectURL string
+
+	// PKCE adds an S256 code challenge to the authorization URL and the
+	// matching verifier to the code exchange. The verifier is derived from
+	// the state, so nothing has to be stored in between.
+	PKCE bool
+
+	// ErrorHandler, if set, renders failures instead of the default plain
+	// text response. err is an *OAuthError for errors passed back by Salla
+	// (see IsAccessDenied) and wraps ErrInvalidState or ErrStateExpired for
+	// forged or stale callbacks.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// NewOAuthCallbackHandler creates a handler for config.RedirectURI that
+// verifies states with states and passes every new token to onToken, which
+// would typically look up the merchant with UserInfo and save the token to
+// a TokenStore
+func NewOAuthCallbackHandler(config *OAuthConfig, states *StateSigner, onToken func(ctx context.Context, token *Token) error) *OAuthCallbackHandler {
+	return &OAuthCallbackHandler{
+		config:  config,
+		states:  states,
+		onToken: onToken,
+	}
+}
+
+// AuthorizationURL issues a state and returns the authorization URL to send
+// the merchant to. The state is also set as a cookie on w, so the callback
+// is only accepted from the same browser. After authorizing, the merchant
+// is redirected to returnTo, which must be a path on the app such as
+// "/dashboard", or to RedirectURL if returnTo is empty.
+func (h *OAuthCallbackHandler) AuthorizationURL(w http.ResponseWriter, returnTo string) (string, error) {
+	if returnTo != "" && !isLocalPath(returnTo) {
+		return "", fmt.Errorf("return path must be a local path, got %q", returnTo)
+	}
+
+	state, err := h.states.New(returnTo)
+	if err != nil {
+		return "", err
+	}
+
+	// Lax lets the cookie through on the redirect back from Salla
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(h.states.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.config.RedirectURI, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	var opts []AuthCodeOption
+	if h.PKCE {
+		opts = append(opts, S256ChallengeOption(h.states.verifier(state)))
+	}
+
+	return h.config.GetAuthorizationURL(state, opts...), nil
+}
+
+// ServeHTTP implements http.Handler
+func (h *OAuthCallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	// Salla reports declined or failed authorizations through the redirect
+	if code := query.Get("error"); code != "" {
+		oauthErr := &OAuthError{
+			Code:        code,
+			Description: query.Get("error_description"),
+			URI:         query.Get("error_uri"),
+		}
+
+		status := http.StatusBadRequest
+		if oauthErr.Code == "access_denied" {
+			status = http.StatusForbidden
+		}
+		h.fail(w, r, status, oauthErr)
+		return
+	}
+
+	state := query.Get("state")
+	returnTo, err := h.states.Verify(state)
+	if err == nil && !issuedTo(r, state) {
+		err = errStateNotIssued
+	}
+	if err != nil {
+		h.fail(w, r, http.StatusBadRequest, fmt.Errorf("failed to verify state: %w", err))
+		return
+	}
+
+	// The state is used up
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/", MaxAge: -1})
+
+	code := query.Get("code")
+	if code == "" {
+		h.fail(w, r, http.StatusBadRequest, errMissingCode)
+		return
+	}
+
+	var opts []AuthCodeOption
+	if h.PKCE {
+		opts = append(opts, VerifierOption(h.states.verifier(state)))
+	}
+
+	token, err := h.config.ExchangeCode(r.Context(), code, opts...)
+	if err != nil {
+		h.fail(w, r, http.StatusBadGateway, fmt.Errorf("failed to exchange code: %w", err))
+		return
+	}
+
+	if err := h.onToken(r.Context(), token); err != nil {
+		h.fail(w, r, http.StatusInternalServerError, fmt.Errorf("failed to handle token: %w", err))
+		return
+	}
+
+	http.Redirect(w, r, h.redirectTarget(returnTo), http.StatusFound)
+}
+
+// issuedTo reports whether state is the one set as a cookie on r's browser
+func issuedTo(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(stateCookie)
+	return err == nil && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) == 1
+}
+
+// redirectTarget returns where to send the merchant after authorizing
+func (h *OAuthCallbackHandler) redirectTarget(returnTo string) string {
+	if returnTo != "" && isLocalPath(returnTo) {
+		return returnTo
+	}
+	if h.RedirectURL != "" {
+		return h.RedirectURL
+	}
+	return "/"
+}
+
+// fail renders err through ErrorHandler or as a plain text response
+func (h *OAuthCallbackHandler) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if h.ErrorHandler != nil {
+		h.ErrorHandler(w, r, err)
+		return
+	}
+
+	message := "Authorization failed"
+	switch {
+	case IsAccessDenied(err):
+		message = "Authorization was denied"
+	case errors.Is(err, ErrStateExpired):
+		message = "Authorization request expired, please try again"
+	case errors.Is(err, ErrInvalidState), errors.Is(err, errMissingCode):
+		message = "Invalid authorization callback"
+	}
+	http.Error(w, message, status)
+}
+
+// isLocalPath reports whether p is a path on the current host, so
+// redirecting to it cannot send the merchant to another site
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
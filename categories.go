@@ -1,7 +1,9 @@
 package gosalla
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -62,86 +64,108 @@ type UpdateCategoryRequest struct {
 }
 
 // List retrieves all categories with optional pagination
-func (s *CategoriesService) List(opts *ListOptions) ([]Category, *Pagination, error) {
-	path := "/categories"
-	
-	// Add query parameters
-	if opts != nil {
-		path += fmt.Sprintf("?page=%d&per_page=%d", opts.Page, opts.PerPage)
+func (s *CategoriesService) List(ctx context.Context, opts *ListOptions, reqOpts ...RequestOption) ([]Category, *Response, error) {
+	path, err := addOptions("/categories", opts)
+	if err != nil {
+		return nil, nil, err
 	}
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
 		return nil, nil, err
 	}
+	req.scope = ScopeCategoriesRead
+	req.Operation = "categories.list"
 	
-	var resp CategoriesListResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, nil, err
+	var result CategoriesListResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return resp.Data, resp.Pagination, nil
+	return result.Data, resp, nil
+}
+
+// All returns a Pager over every category, starting at the page in opts
+func (s *CategoriesService) All(ctx context.Context, opts *ListOptions, reqOpts ...RequestOption) *Pager[Category] {
+	return newPager(ctx, opts, func(ctx context.Context, opts *ListOptions) ([]Category, *Response, error) {
+		return s.List(ctx, opts, reqOpts...)
+	})
 }
 
 // Get retrieves a category by ID
-func (s *CategoriesService) Get(id int) (*Category, error) {
+func (s *CategoriesService) Get(ctx context.Context, id int, reqOpts ...RequestOption) (*Category, *Response, error) {
 	path := fmt.Sprintf("/categories/%d", id)
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeCategoriesRead
+	req.Operation = "categories.get"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp CategoryResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result CategoryResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Create creates a new category
-func (s *CategoriesService) Create(category *CreateCategoryRequest) (*Category, error) {
+func (s *CategoriesService) Create(ctx context.Context, category *CreateCategoryRequest, reqOpts ...RequestOption) (*Category, *Response, error) {
 	path := "/categories"
 	
-	req, err := s.client.newRequest("POST", path, category)
+	req, err := s.client.newRequest(ctx, "POST", path, category, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeCategoriesReadWrite
+	req.Operation = "categories.create"
 	
-	var resp CategoryResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result CategoryResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Update updates an existing category
-func (s *CategoriesService) Update(id int, category *UpdateCategoryRequest) (*Category, error) {
+func (s *CategoriesService) Update(ctx context.Context, id int, category *UpdateCategoryRequest, reqOpts ...RequestOption) (*Category, *Response, error) {
 	path := fmt.Sprintf("/categories/%d", id)
 	
-	req, err := s.client.newRequest("PUT", path, category)
+	req, err := s.client.newRequest(ctx, "PUT", path, category, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeCategoriesReadWrite
+	req.Operation = "categories.update"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp CategoryResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result CategoryResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Delete deletes a category
-func (s *CategoriesService) Delete(id int) error {
+func (s *CategoriesService) Delete(ctx context.Context, id int, reqOpts ...RequestOption) (*Response, error) {
 	path := fmt.Sprintf("/categories/%d", id)
 	
-	req, err := s.client.newRequest("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, "DELETE", path, nil, reqOpts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	req.scope = ScopeCategoriesReadWrite
+	req.Operation = "categories.delete"
+	req.ResourceID = strconv.Itoa(id)
 	
 	return s.client.do(req, nil)
 }
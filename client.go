@@ -2,9 +2,12 @@ package gosalla
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -23,11 +26,29 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	userAgent  string
+	language   string
+	logger     *slog.Logger
 	
-	// OAuth configuration and token
+	// Retry behaviour for failed requests (nil disables retries)
+	retryPolicy *RetryPolicy
+	
+	// Client-side rate limiting (nil disables it) and the last quota seen
+	limiter *RateLimiter
+	rate    Rate
+	rateMu  sync.RWMutex
+	
+	// Middleware around every attempt and the resulting handler
+	middleware []Middleware
+	handler    Handler
+	
+	// Telemetry (both optional) and the merchant it is reported for
+	tracer     Tracer
+	metrics    Metrics
+	merchantID int
+	
+	// OAuth configuration and the token source that refreshes the token
 	oauthConfig *OAuthConfig
-	token       *Token
-	tokenMu     sync.RWMutex
+	tokens      *refreshingTokenSource
 	
 	// API resource clients
 	Products   *ProductsService
@@ -37,14 +58,31 @@ type Client struct {
 	Brands     *BrandsService
 }
 
-// NewClient creates a new Salla API client
-func NewClient(oauthConfig *OAuthConfig, token *Token) *Client {
+// NewClient creates a new Salla API client configured by opts. Requests
+// are authorized with the token given by WithToken or WithTokenSource.
+//
+//	client := gosalla.NewClient(
+//		gosalla.WithToken(oauthConfig, token),
+//		gosalla.WithLanguage("en"),
+//	)
+func NewClient(opts ...Option) *Client {
 	c := &Client{
 		baseURL:     DefaultBaseURL,
 		httpClient:  &http.Client{Timeout: 30 * time.Second},
 		userAgent:   DefaultUserAgent,
-		oauthConfig: oauthConfig,
-		token:       token,
+		retryPolicy: DefaultRetryPolicy(),
+		limiter:     NewRateLimiter(0, time.Minute),
+	}
+	
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.tokens == nil {
+		c.tokens = newRefreshingTokenSource(c.oauthConfig, nil)
+	}
+	c.handler = chain(c.middleware, c.roundTrip)
+	if c.tracer != nil || c.metrics != nil {
+		c.tokens.setObserver(c.observeRefresh)
 	}
 	
 	// Initialize service clients
@@ -57,62 +95,119 @@ func NewClient(oauthConfig *OAuthConfig, token *Token) *Client {
 	return c
 }
 
-// SetBaseURL sets a custom base URL for the API
+// SetBaseURL sets a custom base URL for the API.
+//
+// Deprecated: Use WithBaseURL. SetBaseURL races with in-flight requests.
 func (c *Client) SetBaseURL(baseURL string) {
 	c.baseURL = baseURL
 }
 
-// SetHTTPClient sets a custom HTTP client
+// SetHTTPClient sets a custom HTTP client.
+//
+// Deprecated: Use WithHTTPClient. SetHTTPClient races with in-flight requests.
 func (c *Client) SetHTTPClient(httpClient *http.Client) {
 	c.httpClient = httpClient
 }
 
-// SetUserAgent sets a custom user agent
+// SetUserAgent sets a custom user agent.
+//
+// Deprecated: Use WithUserAgent. SetUserAgent races with in-flight requests.
 func (c *Client) SetUserAgent(userAgent string) {
 	c.userAgent = userAgent
 }
 
+// RateLimit returns the API quota reported by the most recent response
+// that carried rate limit headers (thread-safe)
+func (c *Client) RateLimit() Rate {
+	c.rateMu.RLock()
+	defer c.rateMu.RUnlock()
+	return c.rate
+}
+
 // GetToken returns the current access token (thread-safe)
 func (c *Client) GetToken() *Token {
-	c.tokenMu.RLock()
-	defer c.tokenMu.RUnlock()
-	return c.token
+	return c.tokens.current()
 }
 
 // SetToken sets a new access token (thread-safe)
 func (c *Client) SetToken(token *Token) {
-	c.tokenMu.Lock()
-	defer c.tokenMu.Unlock()
-	c.token = token
+	c.tokens.set(token)
 }
 
-// RefreshTokenIfNeeded refreshes the access token if it's expired or about to expire
-func (c *Client) RefreshTokenIfNeeded() error {
-	c.tokenMu.Lock()
-	defer c.tokenMu.Unlock()
-	
-	// Check if token is still valid (with 5-minute buffer)
-	if c.token != nil && time.Now().Add(5*time.Minute).Before(c.token.Expiry) {
-		return nil
-	}
-	
-	if c.token == nil || c.token.RefreshToken == "" {
-		return fmt.Errorf("no refresh token available")
-	}
-	
-	newToken, err := c.oauthConfig.RefreshToken(c.token.RefreshToken)
+// OnTokenRefreshed registers a callback that receives every token the client
+// obtains by refreshing. Salla rotates refresh tokens, so the callback should
+// persist the new token; the previous refresh token stops working.
+func (c *Client) OnTokenRefreshed(fn func(*Token)) {
+	c.tokens.setOnRefresh(fn)
+}
+
+// OnReauthorizationRequired registers a callback that is invoked when the
+// token can no longer be refreshed, e.g. because the merchant revoked the
+// app. Requests fail with an error matching ErrReauthorizationRequired until
+// a new token is set.
+func (c *Client) OnReauthorizationRequired(fn func(err error)) {
+	c.tokens.setOnReauthorizationRequired(fn)
+}
+
+// RefreshTokenIfNeeded refreshes the access token if it's expired or about to expire.
+// Concurrent callers share a single refresh request; ctx only bounds how long
+// this caller waits for it.
+func (c *Client) RefreshTokenIfNeeded(ctx context.Context) error {
+	_, err := c.tokens.Token(ctx)
+	return err
+}
+
+// authorize sets the Authorization header of req from a valid token,
+// refreshing it first if needed, and returns the token used
+func (c *Client) authorize(req *http.Request) (*Token, error) {
+	token, err := c.tokens.Token(req.Context())
 	if err != nil {
-		return fmt.Errorf("failed to refresh token: %w", err)
+		// A grant that must be reauthorized is not used any further
+		if errors.Is(err, ErrReauthorizationRequired) {
+			return nil, err
+		}
+		
+		// Fall back to a token that is about to expire but still works
+		token = c.tokens.current()
+		if token == nil || !token.Valid() {
+			return nil, err
+		}
 	}
 	
-	c.token = newToken
-	return nil
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+	return token, nil
 }
 
-// newRequest creates a new HTTP request with proper headers and authentication
-func (c *Client) newRequest(method, path string, body interface{}) (*http.Request, error) {
-	url := fmt.Sprintf("%s%s", c.baseURL, path)
+// Request is an API request together with the gosalla operation it
+// belongs to, as seen by middleware
+type Request struct {
+	*http.Request
+	
+	// Operation names the service call, e.g. "products.get". It is empty
+	// for requests that are not made by a service method.
+	Operation string
+	
+	// ResourceID is the ID of the resource the call acts on, if any
+	ResourceID string
 	
+	// scope, if set, must have been granted to the token
+	scope Scope
+	
+	// cancel, if set, releases the timeout set by WithTimeout
+	cancel context.CancelFunc
+	
+	// attempt is the number of the attempt being made
+	attempt int
+}
+
+// newRequest creates a new HTTP request with proper headers and authentication.
+// The request is bound to ctx, which governs the round trip and body decoding.
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}, opts ...RequestOption) (*Request, error) {
+	return c.newRequestURL(ctx, method, fmt.Sprintf("%s%s", c.baseURL, path), body, opts...)
+}
+
+// newRequestURL creates a request like newRequest for an absolute URL
+func (c *Client) newRequestURL(ctx context.Context, method, url string, body interface{}, opts ...RequestOption) (*Request, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -122,7 +217,7 @@ func (c *Client) newRequest(method, path string, body interface{}) (*http.Reques
 		bodyReader = bytes.NewBuffer(jsonBody)
 	}
 	
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -131,47 +226,296 @@ func (c *Client) newRequest(method, path string, body interface{}) (*http.Reques
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", c.userAgent)
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
 	
 	// Add authorization header
-	c.tokenMu.RLock()
-	if c.token != nil && c.token.AccessToken != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token.AccessToken))
+	if token := c.tokens.current(); token != nil && token.AccessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
 	}
-	c.tokenMu.RUnlock()
 	
-	return req, nil
+	r := &Request{Request: req}
+	for _, opt := range opts {
+		opt(r)
+	}
+	
+	return r, nil
 }
 
-// do executes an HTTP request and handles the response
-func (c *Client) do(req *http.Request, v interface{}) error {
-	// Refresh token if needed before making the request
-	if err := c.RefreshTokenIfNeeded(); err != nil {
-		// Update authorization header with new token
-		c.tokenMu.RLock()
-		if c.token != nil && c.token.AccessToken != "" {
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token.AccessToken))
-		}
-		c.tokenMu.RUnlock()
+// do executes an HTTP request and handles the response, retrying failed
+// attempts according to the client's retry policy, and reports the call to
+// the tracer and metrics. The returned Response is non-nil whenever Salla
+// answered, including for API errors.
+func (c *Client) do(req *Request, v interface{}) (*Response, error) {
+	if req.cancel != nil {
+		defer req.cancel()
+	}
+	
+	if c.tracer == nil && c.metrics == nil {
+		return c.send(req, v)
+	}
+	
+	// Requests made with Do are named after their method unless given an operation
+	name := req.Operation
+	if name == "" {
+		name = req.Method
+	}
+	
+	start := time.Now()
+	ctx, span := startSpan(req.Context(), c.tracer, "salla "+name,
+		slog.String("salla.operation", req.Operation),
+		slog.String("http.method", req.Method),
+		slog.String("http.path", req.URL.Path),
+		slog.Int("salla.merchant", c.merchantID),
+	)
+	req.Request = req.Request.WithContext(ctx)
+	
+	response, err := c.send(req, v)
+	
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
 	}
+	retries := req.attempt - 1
 	
-	resp, err := c.httpClient.Do(req)
+	span.SetAttributes(slog.Int("http.status_code", statusCode), slog.Int("salla.retries", retries))
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		span.RecordError(err)
 	}
-	defer resp.Body.Close()
+	span.End()
 	
-	// Check for errors
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return parseErrorResponse(resp)
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(RequestMetric{
+			Operation:  req.Operation,
+			Method:     req.Method,
+			Merchant:   c.merchantID,
+			StatusCode: statusCode,
+			Retries:    retries,
+			Duration:   time.Since(start),
+			Err:        err,
+		})
 	}
 	
-	// Parse response if a destination is provided
-	if v != nil {
-		body, err := io.ReadAll(resp.Body)
+	return response, err
+}
+
+// send makes the attempts of a call until one succeeds or the retry policy
+// gives up
+func (c *Client) send(req *Request, v interface{}) (*Response, error) {
+	reauthorized := false
+	
+	for attempt := 1; ; attempt++ {
+		req.attempt = attempt
+		
+		var response *Response
+		var statusCode int
+		var header http.Header
+		
+		// Refresh the token if needed and apply it to this attempt
+		token, err := c.authorize(req.Request)
+		if err != nil {
+			return nil, err
+		}
+		
+		// Fail fast instead of sending a request Salla would reject
+		if err := token.checkScope(req.scope); err != nil {
+			return nil, err
+		}
+		
+		// Wait for the rate limiter before every attempt
+		if c.limiter != nil {
+			if err := c.limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+		
+		start := time.Now()
+		resp, err := c.handler(req)
+		if c.logger != nil {
+			logRoundTrip(req.Context(), c.logger, req, resp, err, attempt, time.Since(start))
+		}
+		if err != nil {
+			// Only failures to reach Salla are retried; errors from
+			// middleware are returned as they are
+			var transportErr *TransportError
+			if !errors.As(err, &transportErr) || req.Context().Err() != nil {
+				return nil, err
+			}
+		} else {
+			c.observeRateLimit(resp)
+			response = newResponse(resp)
+			
+			// Check for errors
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				defer resp.Body.Close()
+				return response, decodeResponse(response, v)
+			}
+			err = parseErrorResponse(resp)
+			resp.Body.Close()
+			statusCode, header = resp.StatusCode, resp.Header
+		}
+		
+		// A rejected token gets one refresh and a retry that does not count
+		// against the retry policy
+		if statusCode == http.StatusUnauthorized && !reauthorized {
+			reauthorized = true
+			_, refreshErr := c.tokens.refresh(req.Context(), token)
+			if refreshErr == nil {
+				if err := rewindBody(req.Request); err != nil {
+					return response, err
+				}
+				attempt--
+				continue
+			}
+			if errors.Is(refreshErr, ErrReauthorizationRequired) {
+				return response, refreshErr
+			}
+		}
+		
+		// Decide whether the failed attempt should be retried
+		if !c.retryPolicy.retryable(req.Request, err, attempt) {
+			return response, err
+		}
+		
+		delay, ok := c.retryPolicy.delay(attempt, header)
+		if !ok {
+			return response, err
+		}
+		
+		if c.logger != nil {
+			c.logger.LogAttrs(req.Context(), slog.LevelDebug, "retrying request",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Int("attempt", attempt),
+				slog.Int("status", statusCode),
+				slog.Duration("delay", delay),
+			)
+		}
+		
+		if c.retryPolicy.OnRetry != nil {
+			c.retryPolicy.OnRetry(RetryEvent{
+				Request:    req.Request,
+				Attempt:    attempt,
+				StatusCode: statusCode,
+				Err:        err,
+				Delay:      delay,
+			})
+		}
+		
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return response, err
+		}
+		
+		// Replay the request body for the next attempt
+		if err := rewindBody(req.Request); err != nil {
+			return response, err
+		}
+	}
+}
+
+// roundTrip sends req over the client's HTTP client
+func (c *Client) roundTrip(req *Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req.Request)
+	if err != nil {
+		return nil, &TransportError{Err: err}
+	}
+	return resp, nil
+}
+
+// observeRefresh starts tracing a token refresh and returns the function
+// that reports its outcome
+func (c *Client) observeRefresh(ctx context.Context) (context.Context, func(refreshed bool, err error)) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, c.tracer, "salla token.refresh", slog.Int("salla.merchant", c.merchantID))
+	
+	return ctx, func(refreshed bool, err error) {
+		span.SetAttributes(slog.Bool("salla.refreshed", refreshed))
 		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			span.RecordError(err)
 		}
+		span.End()
 		
+		if c.metrics != nil {
+			c.metrics.ObserveTokenRefresh(TokenRefreshMetric{
+				Merchant:  c.merchantID,
+				Refreshed: refreshed,
+				Duration:  time.Since(start),
+				Err:       err,
+			})
+		}
+	}
+}
+
+// rewindBody resets the body of req so it can be sent again
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	req.Body = body
+	return nil
+}
+
+// observeRateLimit records the quota reported by resp and feeds it to the limiter
+func (c *Client) observeRateLimit(resp *http.Response) {
+	now := time.Now()
+	
+	if rate, ok := parseRate(resp.Header, now); ok {
+		c.rateMu.Lock()
+		c.rate = rate
+		c.rateMu.Unlock()
+		
+		if c.limiter != nil {
+			c.limiter.observe(rate, now)
+		}
+	}
+	
+	// Hold back every caller, not just this one, when Salla asks us to slow down
+	if resp.StatusCode == http.StatusTooManyRequests && c.limiter != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			c.limiter.holdUntil(now.Add(d))
+		}
+	}
+}
+
+// decodeResponse reads the response envelope into r and the full body into
+// v if a destination is provided
+func decodeResponse(r *Response, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	
+	var envelope struct {
+		Success    bool        `json:"success"`
+		Code       int         `json:"code"`
+		Pagination *Pagination `json:"pagination"`
+		Cursor     *Cursor     `json:"cursor"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		r.Success = envelope.Success
+		r.Code = envelope.Code
+		r.Pagination = envelope.Pagination
+		
+		// Cursor paginated endpoints may report the cursor next to the data
+		if envelope.Cursor != nil {
+			if r.Pagination == nil {
+				r.Pagination = &Pagination{}
+			}
+			r.Pagination.Cursor = envelope.Cursor
+		}
+	}
+	
+	if v != nil {
 		if err := json.Unmarshal(body, v); err != nil {
 			return fmt.Errorf("failed to parse response: %w", err)
 		}
@@ -180,17 +524,27 @@ func (c *Client) do(req *http.Request, v interface{}) error {
 	return nil
 }
 
-// Response represents a standard API response wrapper
+// Response wraps the HTTP response of an API call together with the
+// metadata Salla returns alongside the data
 type Response struct {
-	Success bool        `json:"success"`
-	Code    int         `json:"code"`
-	Data    interface{} `json:"data"`
+	*http.Response
+	
+	Success    bool        // "success" field of the response envelope
+	Code       int         // "code" field of the response envelope
+	RequestID  string      // request ID assigned by Salla, if reported
+	Rate       Rate        // rate limit state reported with this response
+	Pagination *Pagination // pagination metadata, set for list endpoints
 }
 
-// ListResponse represents a paginated list response
-type ListResponse struct {
-	Success bool        `json:"success"`
-	Code    int         `json:"code"`
-	Data    interface{} `json:"data"`
-	Pagination *Pagination `json:"pagination,omitempty"`
+// newResponse wraps resp and extracts the metadata carried in its headers
+func newResponse(resp *http.Response) *Response {
+	r := &Response{Response: resp}
+	
+	r.RequestID = requestID(resp.Header)
+	
+	if rate, ok := parseRate(resp.Header, time.Now()); ok {
+		r.Rate = rate
+	}
+	
+	return r
 }
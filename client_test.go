@@ -1,8 +1,15 @@
 package gosalla
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -19,7 +26,7 @@ func TestNewClient(t *testing.T) {
 		Expiry:      time.Now().Add(1 * time.Hour),
 	}
 	
-	client := NewClient(config, token)
+	client := NewClient(WithToken(config, token))
 	
 	if client == nil {
 		t.Fatal("Expected client to be created")
@@ -55,7 +62,7 @@ func TestNewClient(t *testing.T) {
 }
 
 func TestSetBaseURL(t *testing.T) {
-	client := NewClient(&OAuthConfig{}, &Token{})
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{}))
 	customURL := "https://custom.api.url"
 	
 	client.SetBaseURL(customURL)
@@ -66,7 +73,7 @@ func TestSetBaseURL(t *testing.T) {
 }
 
 func TestSetUserAgent(t *testing.T) {
-	client := NewClient(&OAuthConfig{}, &Token{})
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{}))
 	customAgent := "CustomAgent/1.0"
 	
 	client.SetUserAgent(customAgent)
@@ -77,7 +84,7 @@ func TestSetUserAgent(t *testing.T) {
 }
 
 func TestGetSetToken(t *testing.T) {
-	client := NewClient(&OAuthConfig{}, &Token{AccessToken: "initial"})
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "initial"}))
 	
 	// Test Get
 	token := client.GetToken()
@@ -125,9 +132,9 @@ func TestTokenValid(t *testing.T) {
 }
 
 func TestNewRequest(t *testing.T) {
-	client := NewClient(&OAuthConfig{}, &Token{AccessToken: "test_token"})
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test_token"}))
 	
-	req, err := client.newRequest("GET", "/test", nil)
+	req, err := client.newRequest(context.Background(), "GET", "/test", nil)
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
@@ -164,10 +171,10 @@ func TestNewRequest(t *testing.T) {
 }
 
 func TestNewRequestWithBody(t *testing.T) {
-	client := NewClient(&OAuthConfig{}, &Token{})
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{}))
 	
 	body := map[string]string{"key": "value"}
-	req, err := client.newRequest("POST", "/test", body)
+	req, err := client.newRequest(context.Background(), "POST", "/test", body)
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
@@ -185,11 +192,10 @@ func TestDoWithError(t *testing.T) {
 	}))
 	defer server.Close()
 	
-	client := NewClient(&OAuthConfig{}, &Token{AccessToken: "test"})
-	client.SetBaseURL(server.URL)
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}), WithBaseURL(server.URL))
 	
-	req, _ := client.newRequest("GET", "/test", nil)
-	err := client.do(req, nil)
+	req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+	_, err := client.do(req, nil)
 	
 	if err == nil {
 		t.Fatal("Expected error, got nil")
@@ -199,3 +205,695 @@ func TestDoWithError(t *testing.T) {
 		t.Error("Expected NotFoundError")
 	}
 }
+
+func TestDoContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}), WithBaseURL(server.URL))
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	
+	req, _ := client.newRequest(ctx, "GET", "/test", nil)
+	_, err := client.do(req, nil)
+	
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	
+	if ctx.Err() == nil {
+		t.Error("Expected context to be done")
+	}
+}
+
+func TestDoRetriesServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+	
+	var retries []RetryEvent
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}),
+		WithBaseURL(server.URL),
+		WithRetryPolicy(&RetryPolicy{
+			MaxAttempts: 3,
+			MinBackoff:  time.Millisecond,
+			MaxBackoff:  time.Second,
+			OnRetry: func(e RetryEvent) {
+				retries = append(retries, e)
+			},
+		}),
+	)
+	
+	req, _ := client.newRequest(context.Background(), "PUT", "/test", map[string]string{"key": "value"})
+	if _, err := client.do(req, nil); err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	
+	if len(retries) != 2 || retries[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 2 retry events for status 503, got %+v", retries)
+	}
+}
+
+func TestDoDoesNotRetryNonIdempotent(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}),
+		WithBaseURL(server.URL),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}),
+	)
+	
+	req, _ := client.newRequest(context.Background(), "POST", "/test", nil)
+	if _, err := client.do(req, nil); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt for POST, got %d", attempts)
+	}
+}
+
+func TestDoClassifiesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>" + strings.Repeat("x", 8<<10) + "</html>"))
+	}))
+	
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}),
+		WithBaseURL(server.URL),
+		WithRetryPolicy(nil),
+	)
+	
+	req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+	_, err := client.do(req, nil)
+	
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Retryable() || !IsRetryable(err) {
+		t.Fatalf("Expected retryable APIError, got %v", err)
+	}
+	
+	if strings.Contains(err.Error(), "<html>") || len(apiErr.RawBody) != maxRawBody {
+		t.Errorf("Expected body capped in RawBody and kept out of the message, got %q (%d bytes)", err, len(apiErr.RawBody))
+	}
+	
+	if IsTransportError(err) {
+		t.Error("Expected an API error not to be a transport error")
+	}
+	
+	// Nothing listens once the server is closed
+	server.Close()
+	
+	req, _ = client.newRequest(context.Background(), "GET", "/test", nil)
+	_, err = client.do(req, nil)
+	
+	if !IsTransportError(err) || !IsRetryable(err) {
+		t.Errorf("Expected retryable TransportError, got %v", err)
+	}
+	
+	if IsRetryable(&APIError{StatusCode: http.StatusUnprocessableEntity}) {
+		t.Error("Expected 422 not to be retryable")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	
+	if d, ok := parseRetryAfter("120", now); !ok || d != 2*time.Minute {
+		t.Errorf("Expected 2m, got %v (ok=%v)", d, ok)
+	}
+	
+	date := now.Add(30 * time.Second).Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date, now); !ok || d != 30*time.Second {
+		t.Errorf("Expected 30s, got %v (ok=%v)", d, ok)
+	}
+	
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("Expected invalid Retry-After to be rejected")
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "120")
+		w.Header().Set("X-RateLimit-Remaining", "119")
+		w.Header().Set("X-RateLimit-Reset", "60")
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+	
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}), WithBaseURL(server.URL))
+	
+	req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+	if _, err := client.do(req, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	rate := client.RateLimit()
+	if rate.Limit != 120 || rate.Remaining != 119 {
+		t.Errorf("Expected limit 120 and remaining 119, got %+v", rate)
+	}
+	
+	if rate.Reset.Before(time.Now().Add(50 * time.Second)) {
+		t.Errorf("Expected reset about a minute from now, got %v", rate.Reset)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := NewRateLimiter(2, 100*time.Millisecond)
+	ctx := context.Background()
+	
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected the third request to wait for a refill, took %v", elapsed)
+	}
+	
+	// An exhausted quota reported by the server blocks until the reset
+	limiter.observe(Rate{Limit: 2, Remaining: 0, Reset: time.Now().Add(time.Hour)}, time.Now())
+	
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Expected Wait to give up when the context expires")
+	}
+}
+
+func TestListReturnsResponseMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Write([]byte(`{
+			"success": true,
+			"code": 200,
+			"data": [{"id": 1, "name": "Shirt"}],
+			"pagination": {"current_page": 1, "last_page": 3, "per_page": 1, "total": 3}
+		}`))
+	}))
+	defer server.Close()
+	
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}), WithBaseURL(server.URL))
+	
+	products, resp, err := client.Products.List(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	if len(products) != 1 || products[0].Name != "Shirt" {
+		t.Errorf("Unexpected products: %+v", products)
+	}
+	
+	if resp.StatusCode != http.StatusOK || !resp.Success || resp.Code != 200 {
+		t.Errorf("Unexpected envelope: status %d, success %v, code %d", resp.StatusCode, resp.Success, resp.Code)
+	}
+	
+	if resp.RequestID != "req-123" {
+		t.Errorf("Expected request ID req-123, got %q", resp.RequestID)
+	}
+	
+	if resp.Rate.Limit != 60 || resp.Rate.Remaining != 10 {
+		t.Errorf("Unexpected rate: %+v", resp.Rate)
+	}
+	
+	if !resp.Pagination.HasNextPage() || resp.Pagination.Total != 3 {
+		t.Errorf("Unexpected pagination: %+v", resp.Pagination)
+	}
+}
+
+func TestDoChecksScope(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"success": true, "code": 200, "data": {"id": 1}}`))
+	}))
+	defer server.Close()
+	
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{
+		AccessToken: "test",
+		Expiry:      time.Now().Add(time.Hour),
+		Scopes:      []Scope{ScopeOfflineAccess, ScopeProductsReadWrite, ScopeOrdersRead},
+	}), WithBaseURL(server.URL))
+	
+	if _, _, err := client.Products.Get(context.Background(), 1); err != nil {
+		t.Errorf("Expected read_write to grant read, got %v", err)
+	}
+	
+	if _, _, err := client.Orders.Get(context.Background(), 1); err != nil {
+		t.Errorf("Expected orders.read to be granted, got %v", err)
+	}
+	
+	_, _, err := client.Customers.Create(context.Background(), &CreateCustomerRequest{})
+	if !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("Expected ErrInsufficientScope, got %v", err)
+	}
+	
+	var scopeErr *ScopeError
+	if !errors.As(err, &scopeErr) || scopeErr.Required != ScopeCustomersReadWrite {
+		t.Errorf("Expected missing scope %s, got %v", ScopeCustomersReadWrite, err)
+	}
+	
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected the rejected call not to be sent, got %d requests", n)
+	}
+}
+
+// newRefreshTestClient returns a client whose token refreshes hand out
+// "refreshed" access tokens and are counted in calls
+func newRefreshTestClient(baseURL string, token *Token, calls *int32) *Client {
+	client := NewClient(WithToken(&OAuthConfig{}, token), WithBaseURL(baseURL))
+	client.tokens.refreshFn = func(ctx context.Context, refreshToken string) (*Token, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &Token{
+			AccessToken:  "refreshed",
+			RefreshToken: "rotated",
+			Expiry:       time.Now().Add(time.Hour),
+		}, nil
+	}
+	return client
+}
+
+func TestRefreshTokenSingleFlight(t *testing.T) {
+	var calls int32
+	client := newRefreshTestClient("", &Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(-time.Minute),
+	}, &calls)
+	
+	var refreshed []*Token
+	client.OnTokenRefreshed(func(token *Token) {
+		if client.GetToken() == token {
+			t.Error("Expected the token to be published after the callback")
+		}
+		refreshed = append(refreshed, token)
+	})
+	
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.RefreshTokenIfNeeded(context.Background()); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	
+	if calls != 1 {
+		t.Errorf("Expected 1 refresh, got %d", calls)
+	}
+	
+	if len(refreshed) != 1 || refreshed[0].RefreshToken != "rotated" {
+		t.Errorf("Expected callback with the rotated token, got %+v", refreshed)
+	}
+	
+	if client.GetToken().AccessToken != "refreshed" {
+		t.Errorf("Expected client to hold the refreshed token, got %s", client.GetToken().AccessToken)
+	}
+}
+
+func TestDoAppliesRefreshedToken(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	
+	var calls int32
+	client := newRefreshTestClient(server.URL, &Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(-time.Minute),
+	}, &calls)
+	
+	req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+	if _, err := client.do(req, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	if auth != "Bearer refreshed" {
+		t.Errorf("Expected request to use the refreshed token, got %q", auth)
+	}
+}
+
+func TestDoRefreshesOnUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer refreshed" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"success": false, "message": "Unauthenticated"}`))
+			return
+		}
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+	
+	var calls int32
+	client := newRefreshTestClient(server.URL, &Token{
+		AccessToken:  "revoked",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour),
+	}, &calls)
+	
+	req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+	if _, err := client.do(req, nil); err != nil {
+		t.Fatalf("Expected success after refreshing, got %v", err)
+	}
+	
+	if calls != 1 {
+		t.Errorf("Expected 1 refresh, got %d", calls)
+	}
+}
+
+func TestWrappedTokenSourceNearExpiry(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	
+	// The source refreshes on its own schedule, so a token within the
+	// client's refresh margin is still used
+	source := StaticTokenSource(&Token{
+		AccessToken: "expiring",
+		Expiry:      time.Now().Add(time.Minute),
+	})
+	client := NewClient(WithTokenSource(source), WithBaseURL(server.URL))
+	
+	for i := 0; i < 2; i++ {
+		req, _ := client.newRequest(context.Background(), "GET", "/test", nil)
+		if _, err := client.do(req, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if auth != "Bearer expiring" {
+			t.Errorf("Expected request to use the source's token, got %q", auth)
+		}
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "data": {"id": 1, "name": "` + r.Header.Get("Authorization") + `"}}`))
+	}))
+	defer server.Close()
+	
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+	store.Save(ctx, 1, &Token{AccessToken: "merchant-1", Expiry: time.Now().Add(time.Hour)})
+	store.Save(ctx, 2, &Token{AccessToken: "merchant-2", Expiry: time.Now().Add(time.Hour)})
+	
+	pool := NewClientPool(&OAuthConfig{}, store, &ClientPoolOptions{
+		ClientOptions: []Option{WithBaseURL(server.URL)},
+	})
+	defer pool.Close()
+	
+	if pool.For(1) != pool.For(1) {
+		t.Error("Expected the same client for the same merchant")
+	}
+	
+	if pool.For(1).httpClient != pool.For(2).httpClient {
+		t.Error("Expected clients to share one http.Client")
+	}
+	
+	product, _, err := pool.For(2).Products.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	if product.Name != "Bearer merchant-2" {
+		t.Errorf("Expected request with merchant 2's token, got %q", product.Name)
+	}
+	
+	// Clients that cannot authorize are not kept
+	if _, _, err := pool.For(3).Products.Get(ctx, 1); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Expected ErrTokenNotFound for a merchant without a token, got %v", err)
+	}
+	
+	store.Save(ctx, 4, &Token{AccessToken: "merchant-4", Expiry: time.Now().Add(-time.Hour)})
+	if _, _, err := pool.For(4).Products.Get(ctx, 1); !errors.Is(err, ErrReauthorizationRequired) {
+		t.Errorf("Expected ErrReauthorizationRequired for an expired token, got %v", err)
+	}
+	
+	if pool.Len() != 2 {
+		t.Errorf("Expected the failed clients to be dropped, %d left", pool.Len())
+	}
+	
+	if evicted := pool.EvictIdle(0); evicted != 2 || pool.Len() != 0 {
+		t.Errorf("Expected both clients to be evicted, evicted %d, %d left", evicted, pool.Len())
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	var attempts int32
+	var last *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		case "/products/1/status":
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte(`{"success": true, "data": {"id": 1}}`))
+	}))
+	defer server.Close()
+	
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}),
+		WithBaseURL(server.URL),
+		WithUserAgent("test-agent"),
+		WithLanguage("ar"),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	ctx := context.Background()
+	
+	if _, _, err := client.Products.Get(ctx, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last.Header.Get("Accept-Language") != "ar" || last.Header.Get("User-Agent") != "test-agent" {
+		t.Errorf("Expected the client defaults, got %v", last.Header)
+	}
+	
+	if _, _, err := client.Products.Get(ctx, 1, WithAcceptLanguage("en"), WithHeader("X-Trace", "abc"), WithQuery("format", "light")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last.Header.Get("Accept-Language") != "en" || last.Header.Get("X-Trace") != "abc" || last.URL.Query().Get("format") != "light" {
+		t.Errorf("Expected the request options to apply, got %v %v", last.Header, last.URL)
+	}
+	
+	// The key is sent, but a POST request is still not retried
+	_, err := client.Products.ChangeStatus(ctx, 1, "sale", WithIdempotencyKey("key-1"))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the 503 to be returned, got %v", err)
+	}
+	if attempts != 1 || last.Header.Get("Idempotency-Key") != "key-1" {
+		t.Errorf("Expected 1 attempt with the key, got %d", attempts)
+	}
+	
+	req, _ := client.newRequest(ctx, "GET", "/slow", nil, WithTimeout(10*time.Millisecond))
+	if _, err := client.do(req, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the timeout to end the call, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "data": {"id": 7, "name": "` + r.Header.Get("X-Signed") + `"}}`))
+	}))
+	defer server.Close()
+	
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *Request) (*http.Response, error) {
+				calls = append(calls, name+" "+req.Operation+" "+req.ResourceID)
+				return next(req)
+			}
+		}
+	}
+	
+	// Fail the first attempt to check that retries pass through the chain
+	failures := 1
+	faults := func(next Handler) Handler {
+		return func(req *Request) (*http.Response, error) {
+			if failures > 0 {
+				failures--
+				return nil, &TransportError{Err: errors.New("injected fault")}
+			}
+			req.Header.Set("X-Signed", "yes")
+			return next(req)
+		}
+	}
+	
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}),
+		WithBaseURL(server.URL),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+		WithMiddleware(record("first"), record("second")),
+		WithMiddleware(faults),
+	)
+	
+	product, _, err := client.Products.Get(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	if product.Name != "yes" {
+		t.Errorf("Expected the middleware to modify the request, got %q", product.Name)
+	}
+	
+	want := []string{"first products.get 7", "second products.get 7", "first products.get 7", "second products.get 7"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected middleware to run in order for every attempt, got %v", calls)
+	}
+}
+
+func TestMiddlewareErrorNotRetried(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"success": true, "data": {"id": 7}}`))
+	}))
+	defer server.Close()
+	
+	errDenied := errors.New("denied by policy")
+	attempts := 0
+	deny := func(next Handler) Handler {
+		return func(req *Request) (*http.Response, error) {
+			attempts++
+			return nil, errDenied
+		}
+	}
+	
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}),
+		WithBaseURL(server.URL),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}),
+		WithMiddleware(deny),
+	)
+	
+	_, _, err := client.Products.Get(context.Background(), 7)
+	var transportErr *TransportError
+	if err != errDenied || errors.As(err, &transportErr) {
+		t.Fatalf("Expected the middleware error as is, got %v", err)
+	}
+	
+	if attempts != 1 || requests != 0 {
+		t.Errorf("Expected 1 attempt and no request, got %d attempts and %d requests", attempts, requests)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "120")
+		w.Header().Set("X-RateLimit-Remaining", "119")
+		w.Write([]byte(`{"success": true, "code": 200, "data": {"id": 3, "first_name": "Jane", "email": "jane@example.com", "mobile": "555123456"}}`))
+	}))
+	defer server.Close()
+	
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	token := &Token{AccessToken: "secret-access", RefreshToken: "secret-refresh"}
+	
+	client := NewClient(WithToken(&OAuthConfig{}, token), WithBaseURL(server.URL), WithLogger(logger))
+	customer, _, err := client.Customers.Get(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	// Logging the body must not get in the way of decoding it
+	if customer.Email != "jane@example.com" {
+		t.Errorf("Expected the customer to be decoded, got %+v", customer)
+	}
+	
+	logger.Info("token", "token", token)
+	
+	out := buf.String()
+	for _, want := range []string{"operation=customers.get", "path=/customers/3", "status=200", "attempt=1", "rate_limit_remaining=119", "duration=", "Jane"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected log to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, secret := range []string{"secret-access", "secret-refresh", "jane@example.com", "555123456"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", secret, out)
+		}
+	}
+}
+
+func TestLoggingTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	client := NewClient(
+		WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}),
+		WithBaseURL(server.URL),
+		WithLogger(logger),
+		WithRetryPolicy(nil),
+	)
+	
+	_, err := client.Do(context.Background(), "GET", "/customers", nil, nil, nil, WithQuery("email", "jane@example.com"))
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("Expected a TransportError, got %v", err)
+	}
+	
+	out := buf.String()
+	if !strings.Contains(out, "error=") || !strings.Contains(out, "/customers") {
+		t.Errorf("Expected the failed request to be logged, got:\n%s", out)
+	}
+	if strings.Contains(out, "jane") {
+		t.Errorf("Expected the query to be redacted, got:\n%s", out)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	form := redactBody([]byte("grant_type=refresh_token&client_id=id&client_secret=s3cret&refresh_token=r3fresh&code=c0de"))
+	if strings.Contains(form, "s3cret") || strings.Contains(form, "r3fresh") || strings.Contains(form, "c0de") || !strings.Contains(form, "client_id=id") {
+		t.Errorf("Unexpected redacted form: %s", form)
+	}
+	
+	body := redactBody([]byte(`{"code": 200, "data": [{"phone": "555", "name": "Shop"}]}`))
+	if body != `{"code":200,"data":[{"name":"Shop","phone":"[REDACTED]"}]}` {
+		t.Errorf("Unexpected redacted JSON: %s", body)
+	}
+	
+	if text := redactBody([]byte("<html>Bad Gateway</html>")); text != "<html>Bad Gateway</html>" {
+		t.Errorf("Expected other bodies to be kept, got %s", text)
+	}
+}
@@ -1,7 +1,9 @@
 package gosalla
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -83,75 +85,117 @@ type UpdateCustomerRequest struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// List retrieves all customers with optional pagination
-func (s *CustomersService) List(opts *ListOptions) ([]Customer, *Pagination, error) {
-	path := "/customers"
+// CustomerListOptions filters and sorts the customers returned by List
+type CustomerListOptions struct {
+	ListOptions
+	SortOptions
 	
-	// Add query parameters
-	if opts != nil {
-		path += fmt.Sprintf("?page=%d&per_page=%d", opts.Page, opts.PerPage)
-	}
+	// Keyword matches the customer's name, email or phone
+	Keyword string `url:"keyword,omitempty"`
+	GroupID int    `url:"group_id,omitempty"`
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	// FromDate and ToDate limit the customers to those registered in a
+	// date range
+	FromDate time.Time `url:"from_date,omitempty"`
+	ToDate   time.Time `url:"to_date,omitempty"`
+}
+
+// List retrieves all customers with optional filters and pagination
+func (s *CustomersService) List(ctx context.Context, opts *CustomerListOptions, reqOpts ...RequestOption) ([]Customer, *Response, error) {
+	path, err := addOptions("/customers", opts)
 	if err != nil {
 		return nil, nil, err
 	}
 	
-	var resp CustomersListResponse
-	if err := s.client.do(req, &resp); err != nil {
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
+	if err != nil {
 		return nil, nil, err
 	}
+	req.scope = ScopeCustomersRead
+	req.Operation = "customers.list"
+	
+	var result CustomersListResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
+	
+	return result.Data, resp, nil
+}
+
+// All returns a Pager over every customer matching opts, starting at the
+// page in opts
+func (s *CustomersService) All(ctx context.Context, opts *CustomerListOptions, reqOpts ...RequestOption) *Pager[Customer] {
+	var filters CustomerListOptions
+	if opts != nil {
+		filters = *opts
+	}
 	
-	return resp.Data, resp.Pagination, nil
+	return newPager(ctx, &filters.ListOptions, func(ctx context.Context, page *ListOptions) ([]Customer, *Response, error) {
+		opts := filters
+		opts.ListOptions = *page
+		return s.List(ctx, &opts, reqOpts...)
+	})
 }
 
 // Get retrieves a customer by ID
-func (s *CustomersService) Get(id int) (*Customer, error) {
+func (s *CustomersService) Get(ctx context.Context, id int, reqOpts ...RequestOption) (*Customer, *Response, error) {
 	path := fmt.Sprintf("/customers/%d", id)
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeCustomersRead
+	req.Operation = "customers.get"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp CustomerResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result CustomerResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Create creates a new customer
-func (s *CustomersService) Create(customer *CreateCustomerRequest) (*Customer, error) {
+func (s *CustomersService) Create(ctx context.Context, customer *CreateCustomerRequest, reqOpts ...RequestOption) (*Customer, *Response, error) {
 	path := "/customers"
 	
-	req, err := s.client.newRequest("POST", path, customer)
+	req, err := s.client.newRequest(ctx, "POST", path, customer, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeCustomersReadWrite
+	req.Operation = "customers.create"
 	
-	var resp CustomerResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result CustomerResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Update updates an existing customer
-func (s *CustomersService) Update(id int, customer *UpdateCustomerRequest) (*Customer, error) {
+func (s *CustomersService) Update(ctx context.Context, id int, customer *UpdateCustomerRequest, reqOpts ...RequestOption) (*Customer, *Response, error) {
 	path := fmt.Sprintf("/customers/%d", id)
 	
-	req, err := s.client.newRequest("PUT", path, customer)
+	req, err := s.client.newRequest(ctx, "PUT", path, customer, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeCustomersReadWrite
+	req.Operation = "customers.update"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp CustomerResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result CustomerResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
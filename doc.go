@@ -11,9 +11,13 @@ categories, and brands, and webhook signature verification.
 # Features
 
   - OAuth 2.0 authentication with automatic token refresh
+  - Signed OAuth state and a ready-made callback handler
+  - PKCE (S256) for public clients
   - Complete API coverage for core resources
   - Webhook handling with HMAC signature verification
-  - Built-in pagination support
+  - Built-in pagination support with lazy iterators over every page
+  - Automatic retries with exponential backoff for 429 and 5xx responses
+  - Client-side rate limiting driven by Salla's rate limit headers
   - Thread-safe operations
   - Zero external dependencies
 
@@ -27,27 +31,39 @@ OAuth Authentication:
 		RedirectURI:  "your_redirect_uri",
 	}
 
-	authURL := config.GetAuthorizationURL("state")
+	states, err := gosalla.NewStateSigner(stateSecret, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	state, err := states.New("")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authURL := config.GetAuthorizationURL(state)
 	// Redirect user to authURL
 
-	// After authorization
-	token, err := config.ExchangeCode("authorization_code")
+	// After authorization, check the state and that it belongs to the
+	// user's session before using the code
+	if _, err := states.Verify(callbackState); err != nil {
+		log.Fatal(err)
+	}
+	token, err := config.ExchangeCode(ctx, "authorization_code")
 	if err != nil {
 		log.Fatal(err)
 	}
 
 API Client Usage:
 
-	client := gosalla.NewClient(config, token)
+	client := gosalla.NewClient(gosalla.WithToken(config, token))
 
 	// List products
-	products, pagination, err := client.Products.List(&gosalla.ListOptions{
-		Page:    1,
-		PerPage: 10,
+	products, resp, err := client.Products.List(ctx, &gosalla.ProductListOptions{
+		ListOptions: gosalla.ListOptions{Page: 1, PerPage: 10},
 	})
 
 	// Create a product
-	product, err := client.Products.Create(&gosalla.CreateProductRequest{
+	product, resp, err := client.Products.Create(ctx, &gosalla.CreateProductRequest{
 		Name:     "My Product",
 		Price:    99.99,
 		Quantity: 100,
@@ -65,11 +81,22 @@ Webhook Handling:
 	http.Handle("/webhook", handler)
 	http.ListenAndServe(":8080", nil)
 
+# Contexts
+
+Every API method and OAuth call takes a context.Context as its first argument.
+The context governs token refresh, the HTTP round trip and response decoding,
+so cancelling it or letting its deadline pass aborts the call:
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	order, resp, err := client.Orders.Get(ctx, orderID)
+
 # Error Handling
 
 The package provides custom error types and helper functions:
 
-	products, _, err := client.Products.List(nil)
+	products, _, err := client.Products.List(ctx, nil)
 	if err != nil {
 		if gosalla.IsNotFoundError(err) {
 			// Handle 404
@@ -78,6 +105,14 @@ The package provides custom error types and helper functions:
 		}
 	}
 
+The helpers use errors.Is, so they also match wrapped errors. Validation
+failures are returned as a *ValidationError listing the messages per field:
+
+	var validationErr *gosalla.ValidationError
+	if errors.As(err, &validationErr) {
+		fmt.Println(validationErr.Fields["name"])
+	}
+
 # Resources
 
 For more information, visit:
@@ -1,26 +1,211 @@
 package gosalla
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
 )
 
+// ErrReauthorizationRequired is reported when a merchant's grant can no
+// longer be refreshed, e.g. because the refresh token was revoked or has
+// expired. The merchant has to authorize (reinstall) the app again.
+var ErrReauthorizationRequired = errors.New("reauthorization required")
+
+// Sentinel errors matched by APIError through errors.Is
+var (
+	// ErrNotFound is matched by 404 Not Found responses
+	ErrNotFound = errors.New("not found")
+	
+	// ErrUnauthorized is matched by 401 Unauthorized responses
+	ErrUnauthorized = errors.New("unauthorized")
+	
+	// ErrRateLimited is matched by 429 Too Many Requests responses
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// maxRawBody caps how much of an error response body is kept in RawBody
+const maxRawBody = 4 << 10
+
+// maxErrorBody caps how much of an error response body is read
+const maxErrorBody = 1 << 20
+
 // APIError represents an error returned by the Salla API
 type APIError struct {
 	StatusCode int                    `json:"status_code"`
+	Code       string                 `json:"code,omitempty"`
 	Message    string                 `json:"message"`
+	RequestID  string                 `json:"request_id,omitempty"`
 	Errors     map[string]interface{} `json:"errors,omitempty"`
-	Response   *http.Response         `json:"-"`
+	
+	// RawBody holds the start of the response body, at most 4 KiB, e.g.
+	// to inspect a gateway error page that is not JSON
+	RawBody []byte `json:"-"`
+	
+	// Response is the HTTP response. Its body has already been read.
+	Response *http.Response `json:"-"`
 }
 
 // Error implements the error interface
 func (e *APIError) Error() string {
+	msg := fmt.Sprintf("salla api error (status %d)", e.StatusCode)
 	if e.Message != "" {
-		return fmt.Sprintf("salla api error (status %d): %s", e.StatusCode, e.Message)
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	return msg
+}
+
+// Is lets errors.Is match ErrNotFound, ErrUnauthorized and ErrRateLimited
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// Retryable reports whether the request may succeed if sent again, i.e.
+// the response was 408 Request Timeout, 429 Too Many Requests or a 5xx
+// other than 501 Not Implemented
+func (e *APIError) Retryable() bool {
+	return isRetryableStatus(e.StatusCode)
+}
+
+// Temporary reports whether the error is temporary. It is the same as
+// Retryable.
+func (e *APIError) Temporary() bool {
+	return e.Retryable()
+}
+
+// isRetryableStatus reports whether a response with statusCode is worth retrying
+func isRetryableStatus(statusCode int) bool {
+	switch {
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooManyRequests:
+		return true
+	case statusCode >= 500 && statusCode != http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// TransportError is returned when a request never got a response from
+// Salla, e.g. because of a DNS failure, a refused connection, a TLS error
+// or a timeout
+type TransportError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("request failed: %v", e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the request timed out
+func (e *TransportError) Timeout() bool {
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// Retryable reports whether the request may succeed if sent again. It is
+// false only if the request was cancelled.
+func (e *TransportError) Retryable() bool {
+	return !errors.Is(e.Err, context.Canceled)
+}
+
+// Temporary reports whether the error is temporary. It is the same as
+// Retryable.
+func (e *TransportError) Temporary() bool {
+	return e.Retryable()
+}
+
+// ValidationError is returned for 422 responses and carries the messages
+// Salla reported for each invalid field
+type ValidationError struct {
+	*APIError
+	Fields map[string][]string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	
+	msg := e.APIError.Error()
+	for _, field := range fields {
+		msg += fmt.Sprintf("; %s: %s", field, strings.Join(e.Fields[field], ", "))
+	}
+	return msg
+}
+
+// Unwrap returns the underlying APIError so errors.As can reach it
+func (e *ValidationError) Unwrap() error {
+	return e.APIError
+}
+
+// OAuthError represents an OAuth error as defined in RFC 6749, either a
+// token endpoint response (section 5.2) or an error passed back to the
+// redirect URI (section 4.1.2.1), in which case StatusCode is zero
+type OAuthError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+}
+
+// Error implements the error interface
+func (e *OAuthError) Error() string {
+	if e.StatusCode == 0 {
+		if e.Description != "" {
+			return fmt.Sprintf("oauth error: %s: %s", e.Code, e.Description)
+		}
+		return fmt.Sprintf("oauth error: %s", e.Code)
+	}
+	if e.Description != "" {
+		return fmt.Sprintf("oauth error (status %d): %s: %s", e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("oauth error (status %d): %s", e.StatusCode, e.Code)
+}
+
+// RequiresReauthorization reports whether the grant used in the request is
+// no longer valid, so retrying cannot succeed without a new authorization
+func (e *OAuthError) RequiresReauthorization() bool {
+	return e.Code == "invalid_grant"
+}
+
+// Is lets errors.Is match ErrReauthorizationRequired
+func (e *OAuthError) Is(target error) bool {
+	return target == ErrReauthorizationRequired && e.RequiresReauthorization()
+}
+
+// parseOAuthError builds an OAuthError from a failed token endpoint response
+func parseOAuthError(statusCode int, body []byte) *OAuthError {
+	oauthErr := &OAuthError{}
+	if err := json.Unmarshal(body, oauthErr); err != nil || oauthErr.Code == "" {
+		// Not an RFC 6749 error body, e.g. an HTML page from a proxy
+		oauthErr = &OAuthError{Code: "server_error", Description: http.StatusText(statusCode)}
 	}
-	return fmt.Sprintf("salla api error (status %d)", e.StatusCode)
+	oauthErr.StatusCode = statusCode
+	return oauthErr
 }
 
 // ErrorResponse represents the structure of error responses from Salla API
@@ -29,26 +214,56 @@ type ErrorResponse struct {
 	Code    int                    `json:"code"`
 	Message string                 `json:"message"`
 	Data    map[string]interface{} `json:"data,omitempty"`
+	Error   *ErrorDetail           `json:"error,omitempty"`
+}
+
+// ErrorDetail is the error object of an error response
+type ErrorDetail struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message"`
+	Fields  map[string][]string `json:"fields,omitempty"`
 }
 
 // parseErrorResponse attempts to parse an error response from the API
 func parseErrorResponse(resp *http.Response) error {
 	apiErr := &APIError{
 		StatusCode: resp.StatusCode,
+		RequestID:  requestID(resp.Header),
 		Response:   resp,
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
 	if err != nil {
 		apiErr.Message = "failed to read error response"
 		return apiErr
 	}
 
+	apiErr.RawBody = body
+	if len(body) > maxRawBody {
+		apiErr.RawBody = body[:maxRawBody:maxRawBody]
+	}
+
+	fields := decodeErrorBody(apiErr, body)
+	if resp.StatusCode == http.StatusUnprocessableEntity || len(fields) > 0 {
+		return &ValidationError{APIError: apiErr, Fields: fields}
+	}
+
+	return apiErr
+}
+
+// decodeErrorBody fills apiErr from a JSON error body and returns its
+// field errors. Fields of an unexpected type, such as the "data": [] of an
+// empty Laravel collection, are skipped rather than failing the body.
+func decodeErrorBody(apiErr *APIError, body []byte) map[string][]string {
 	var errResp ErrorResponse
 	if err := json.Unmarshal(body, &errResp); err != nil {
-		// If we can't parse the error response, just use the raw body
-		apiErr.Message = string(body)
-		return apiErr
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) || typeErr.Field == "" {
+			// Not a JSON error, e.g. an HTML page from a gateway; keep it
+			// out of the message, it is available in RawBody
+			apiErr.Message = http.StatusText(apiErr.StatusCode)
+			return nil
+		}
 	}
 
 	apiErr.Message = errResp.Message
@@ -56,29 +271,70 @@ func parseErrorResponse(resp *http.Response) error {
 		apiErr.Errors = errResp.Data
 	}
 
-	return apiErr
+	var fields map[string][]string
+	if detail := errResp.Error; detail != nil {
+		apiErr.Code = detail.Code
+		if apiErr.Message == "" {
+			apiErr.Message = detail.Message
+		}
+		fields = detail.Fields
+	}
+
+	return fields
+}
+
+// requestID returns the request ID Salla assigned to a response
+func requestID(header http.Header) string {
+	if id := header.Get("X-Request-Id"); id != "" {
+		return id
+	}
+	return header.Get("X-Trace-Id")
 }
 
 // IsNotFoundError checks if the error is a 404 Not Found error
 func IsNotFoundError(err error) bool {
-	if apiErr, ok := err.(*APIError); ok {
-		return apiErr.StatusCode == http.StatusNotFound
-	}
-	return false
+	return errors.Is(err, ErrNotFound)
 }
 
 // IsUnauthorizedError checks if the error is a 401 Unauthorized error
 func IsUnauthorizedError(err error) bool {
-	if apiErr, ok := err.(*APIError); ok {
-		return apiErr.StatusCode == http.StatusUnauthorized
-	}
-	return false
+	return errors.Is(err, ErrUnauthorized)
 }
 
 // IsRateLimitError checks if the error is a 429 Rate Limit error
 func IsRateLimitError(err error) bool {
-	if apiErr, ok := err.(*APIError); ok {
-		return apiErr.StatusCode == http.StatusTooManyRequests
-	}
-	return false
+	return errors.Is(err, ErrRateLimited)
+}
+
+// IsValidationError checks if the error is a 422 validation error. Use
+// errors.As with a *ValidationError to inspect the invalid fields.
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// IsRetryable checks if the request that failed with err may succeed if
+// sent again, i.e. err is a retryable APIError or TransportError
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	return errors.As(err, &r) && r.Retryable()
+}
+
+// IsTransportError checks if the request never got a response from Salla
+func IsTransportError(err error) bool {
+	var transportErr *TransportError
+	return errors.As(err, &transportErr)
+}
+
+// IsReauthorizationRequired checks if the error means the merchant has to
+// authorize the app again
+func IsReauthorizationRequired(err error) bool {
+	return errors.Is(err, ErrReauthorizationRequired)
+}
+
+// IsAccessDenied checks if the error means the merchant declined to
+// authorize the app
+func IsAccessDenied(err error) bool {
+	var oauthErr *OAuthError
+	return errors.As(err, &oauthErr) && oauthErr.Code == "access_denied"
 }
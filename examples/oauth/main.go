@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
-	
+
 	"github.com/abdalgaderserag/gosalla"
 )
 
@@ -13,61 +16,71 @@ func main() {
 	clientID := os.Getenv("SALLA_CLIENT_ID")
 	clientSecret := os.Getenv("SALLA_CLIENT_SECRET")
 	redirectURI := os.Getenv("SALLA_REDIRECT_URI")
-	
-	if clientID == "" || clientSecret == "" || redirectURI == "" {
-		log.Fatal("Please set SALLA_CLIENT_ID, SALLA_CLIENT_SECRET, and SALLA_REDIRECT_URI environment variables")
+	stateSecret := os.Getenv("SALLA_STATE_SECRET")
+
+	if clientID == "" || clientSecret == "" || redirectURI == "" || stateSecret == "" {
+		log.Fatal("Please set SALLA_CLIENT_ID, SALLA_CLIENT_SECRET, SALLA_REDIRECT_URI and SALLA_STATE_SECRET environment variables")
+	}
+
+	callbackURL, err := url.Parse(redirectURI)
+	if err != nil {
+		log.Fatalf("Invalid SALLA_REDIRECT_URI: %v", err)
 	}
-	
+
 	// Create OAuth config
 	oauthConfig := &gosalla.OAuthConfig{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		RedirectURI:  redirectURI,
-		Scopes:       []string{"offline_access"},
+		Scopes:       []gosalla.Scope{gosalla.ScopeOfflineAccess},
 	}
-	
-	// Generate authorization URL
-	state := "random_state_string" // In production, use a secure random string
-	authURL := oauthConfig.GetAuthorizationURL(state)
-	
-	fmt.Println("Visit this URL to authorize the application:")
-	fmt.Println(authURL)
-	fmt.Println()
-	
-	// In a real application, you would:
-	// 1. Redirect the user to the authorization URL
-	// 2. Handle the callback to your redirect URI
-	// 3. Extract the authorization code from the callback
-	// 4. Exchange the code for an access token
-	
-	// For this example, we'll simulate receiving the authorization code
-	fmt.Print("Enter the authorization code from the callback: ")
-	var code string
-	fmt.Scanln(&code)
-	
-	// Exchange the code for an access token
-	token, err := oauthConfig.ExchangeCode(code)
+
+	// States are signed with the secret and expire after the default 10 minutes
+	states, err := gosalla.NewStateSigner([]byte(stateSecret), 0)
 	if err != nil {
-		log.Fatalf("Failed to exchange code for token: %v", err)
+		log.Fatalf("Invalid SALLA_STATE_SECRET: %v", err)
 	}
-	
-	fmt.Println("\nSuccessfully obtained access token!")
-	fmt.Printf("Access Token: %s\n", token.AccessToken[:20]+"...")
-	fmt.Printf("Token Type: %s\n", token.TokenType)
-	fmt.Printf("Expires At: %s\n", token.Expiry)
-	
-	// Save the token for later use
-	// In a real application, you would securely store this token
-	
-	// Example of refreshing the token
-	if token.RefreshToken != "" {
-		fmt.Println("\nRefreshing access token...")
-		newToken, err := oauthConfig.RefreshToken(token.RefreshToken)
+
+	// The callback handler verifies the state and exchanges the code
+	callback := gosalla.NewOAuthCallbackHandler(oauthConfig, states, func(ctx context.Context, token *gosalla.Token) error {
+		fmt.Println("\nSuccessfully obtained access token!")
+		fmt.Printf("Access Token: %s\n", token.AccessToken[:20]+"...")
+		fmt.Printf("Token Type: %s\n", token.TokenType)
+		fmt.Printf("Expires At: %s\n", token.Expiry)
+
+		// In a real application, you would securely store this token
+
+		// Example of refreshing the token
+		if token.RefreshToken != "" {
+			fmt.Println("\nRefreshing access token...")
+			newToken, err := oauthConfig.RefreshToken(ctx, token.RefreshToken)
+			if err != nil {
+				return fmt.Errorf("failed to refresh token: %w", err)
+			}
+
+			fmt.Println("Successfully refreshed access token!")
+			fmt.Printf("New Access Token: %s\n", newToken.AccessToken[:20]+"...")
+		}
+		return nil
+	})
+	callback.RedirectURL = "/done"
+
+	http.Handle(callbackURL.Path, callback)
+
+	// Send the merchant to Salla with a fresh state
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		authURL, err := callback.AuthorizationURL(w, "")
 		if err != nil {
-			log.Fatalf("Failed to refresh token: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		
-		fmt.Println("Successfully refreshed access token!")
-		fmt.Printf("New Access Token: %s\n", newToken.AccessToken[:20]+"...")
-	}
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+
+	http.HandleFunc("/done", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Authorization complete, you can close this window.")
+	})
+
+	fmt.Printf("Open http://%s/ to authorize the application\n", callbackURL.Host)
+	log.Fatal(http.ListenAndServe(callbackURL.Host, nil))
 }
@@ -0,0 +1,10 @@
+module github.com/abdalgaderserag/gosalla/examples/oauth_with_persistence
+
+go 1.21
+
+require (
+	github.com/abdalgaderserag/gosalla v0.0.0
+	github.com/mattn/go-sqlite3 v1.14.52
+)
+
+replace github.com/abdalgaderserag/gosalla => ../..
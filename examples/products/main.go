@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	
 	"github.com/abdalgaderserag/gosalla"
 )
 
 func main() {
+	ctx := context.Background()
+	
 	// Get credentials from environment variables
 	clientID := os.Getenv("SALLA_CLIENT_ID")
 	clientSecret := os.Getenv("SALLA_CLIENT_SECRET")
@@ -18,10 +22,15 @@ func main() {
 		log.Fatal("Please set SALLA_CLIENT_ID, SALLA_CLIENT_SECRET, and SALLA_ACCESS_TOKEN environment variables")
 	}
 	
+	// Log every request; secrets and customer contact details are redacted.
+	// Use slog.LevelDebug to also see the request and response bodies.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	
 	// Create OAuth config
 	oauthConfig := &gosalla.OAuthConfig{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
+		Logger:       logger,
 	}
 	
 	// Create a token (in a real app, you would load this from secure storage)
@@ -31,20 +40,19 @@ func main() {
 	}
 	
 	// Create the Salla API client
-	client := gosalla.NewClient(oauthConfig, token)
+	client := gosalla.NewClient(gosalla.WithToken(oauthConfig, token), gosalla.WithLogger(logger))
 	
 	// List all products
 	fmt.Println("Listing products...")
-	products, pagination, err := client.Products.List(&gosalla.ListOptions{
-		Page:    1,
-		PerPage: 10,
+	products, resp, err := client.Products.List(ctx, &gosalla.ProductListOptions{
+		ListOptions: gosalla.ListOptions{Page: 1, PerPage: 10},
 	})
 	if err != nil {
 		log.Fatalf("Failed to list products: %v", err)
 	}
 	
 	fmt.Printf("\nFound %d products (page %d of %d):\n\n", 
-		len(products), pagination.CurrentPage, pagination.LastPage)
+		len(products), resp.Pagination.CurrentPage, resp.Pagination.LastPage)
 	
 	for i, product := range products {
 		fmt.Printf("%d. %s (ID: %d)\n", i+1, product.Name, product.ID)
@@ -58,7 +66,7 @@ func main() {
 		productID := products[0].ID
 		fmt.Printf("\nFetching product ID %d...\n", productID)
 		
-		product, err := client.Products.Get(productID)
+		product, _, err := client.Products.Get(ctx, productID)
 		if err != nil {
 			log.Fatalf("Failed to get product: %v", err)
 		}
@@ -81,7 +89,7 @@ func main() {
 		Status:      "active",
 	}
 	
-	created, err := client.Products.Create(newProduct)
+	created, _, err := client.Products.Create(ctx, newProduct)
 	if err != nil {
 		log.Fatalf("Failed to create product: %v", err)
 	}
@@ -94,7 +102,7 @@ func main() {
 		Price: 79.99,
 	}
 	
-	updated, err := client.Products.Update(created.ID, updateReq)
+	updated, _, err := client.Products.Update(ctx, created.ID, updateReq)
 	if err != nil {
 		log.Fatalf("Failed to update product: %v", err)
 	}
@@ -103,7 +111,7 @@ func main() {
 	
 	// Delete the product
 	fmt.Println("\nDeleting the product...")
-	if err := client.Products.Delete(created.ID); err != nil {
+	if _, err := client.Products.Delete(ctx, created.ID); err != nil {
 		log.Fatalf("Failed to delete product: %v", err)
 	}
 	
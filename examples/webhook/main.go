@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	
@@ -19,6 +20,7 @@ func main() {
 	
 	// Create webhook handler
 	handler := gosalla.NewWebhookHandler(webhookSecret)
+	handler.Logger = slog.Default()
 	
 	// Register handlers for specific events
 	handler.OnProductCreated(func(event *gosalla.ProductWebhookEvent) error {
@@ -101,7 +103,7 @@ func main() {
 				<pre>
 curl -X POST http://localhost:%s/webhook \
   -H "Content-Type: application/json" \
-  -H "X-Signature: your_signature_here" \
+  -H "X-Salla-Signature: your_signature_here" \
   -d '{
     "event": "product.created",
     "merchant": 12345,
@@ -0,0 +1,216 @@
+package gosalla
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets and personal data in log output
+const redacted = "[REDACTED]"
+
+// sensitiveFields are the body fields whose values are never logged: OAuth
+// secrets and customer contact details
+var sensitiveFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"client_secret": true,
+	"code_verifier": true,
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"email":         true,
+	"phone":         true,
+	"mobile":        true,
+	"mobile_code":   true,
+}
+
+// sensitiveHeaders are the headers whose values are never logged
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Signature",
+	"X-Salla-Signature",
+}
+
+// LogValue implements slog.LogValuer, so logging a Token never reveals the
+// access or refresh token
+func (t *Token) LogValue() slog.Value {
+	if t == nil {
+		return slog.AnyValue(nil)
+	}
+	return slog.GroupValue(
+		slog.String("token_type", t.TokenType),
+		slog.Time("expiry", t.Expiry),
+		slog.String("scope", joinScopes(t.Scopes)),
+	)
+}
+
+// LogValue implements slog.LogValuer, so logging an OAuthConfig never
+// reveals the client secret
+func (c *OAuthConfig) LogValue() slog.Value {
+	if c == nil {
+		return slog.AnyValue(nil)
+	}
+	return slog.GroupValue(
+		slog.String("client_id", c.ClientID),
+		slog.String("redirect_uri", c.RedirectURI),
+		slog.String("scope", c.scope()),
+	)
+}
+
+// logRoundTrip logs a finished attempt of req, with the redacted bodies at
+// debug level
+func logRoundTrip(ctx context.Context, logger *slog.Logger, req *Request, resp *http.Response, err error, attempt int, duration time.Duration) {
+	if resp != nil {
+		logBodies(ctx, logger, req.Request, resp)
+	}
+	logRequest(ctx, logger, req, resp, err, attempt, duration)
+}
+
+// logRequest logs a finished attempt of req
+func logRequest(ctx context.Context, logger *slog.Logger, req *Request, resp *http.Response, err error, attempt int, duration time.Duration) {
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("operation", req.Operation),
+		slog.String("path", req.URL.Path),
+		slog.Int("attempt", attempt),
+		slog.Duration("duration", duration),
+	}
+
+	level := slog.LevelInfo
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if rate, ok := parseRate(resp.Header, time.Now()); ok {
+			attrs = append(attrs, slog.Int("rate_limit_remaining", rate.Remaining))
+		}
+		if resp.StatusCode >= 400 {
+			level = slog.LevelWarn
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactError(err)))
+		level = slog.LevelWarn
+	}
+
+	logger.LogAttrs(ctx, level, "salla request", attrs...)
+}
+
+// logBodies logs the redacted headers and bodies of req and resp at debug
+// level. The response body is read and replaced, so it can still be decoded.
+func logBodies(ctx context.Context, logger *slog.Logger, req *http.Request, resp *http.Response) {
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	var reqBody []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	var respBody []byte
+	if resp.Body != nil {
+		respBody, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "salla request body",
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Any("request_headers", redactHeaders(req.Header)),
+		slog.String("request_body", redactBody(reqBody)),
+		slog.Int("status", resp.StatusCode),
+		slog.Any("response_headers", redactHeaders(resp.Header)),
+		slog.String("response_body", redactBody(respBody)),
+	)
+}
+
+// redactError returns the message of err without the query of the request
+// URL, which may carry customer data such as an email filter
+func redactError(err error) string {
+	msg := err.Error()
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil && u.RawQuery != "" {
+			u.RawQuery = ""
+			msg = strings.ReplaceAll(msg, urlErr.URL, u.String())
+		}
+	}
+	return msg
+}
+
+// redactHeaders returns a copy of h with sensitive values replaced
+func redactHeaders(h http.Header) http.Header {
+	clean := h.Clone()
+	for _, name := range sensitiveHeaders {
+		if clean.Get(name) != "" {
+			clean.Set(name, redacted)
+		}
+	}
+	return clean
+}
+
+// redactBody returns body for logging with sensitive fields replaced. JSON
+// and form encoded bodies are redacted field by field; anything else is
+// logged as is. The result is truncated to maxRawBody bytes.
+func redactBody(body []byte) string {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	var out string
+	var v interface{}
+	if err := json.Unmarshal(trimmed, &v); err == nil {
+		clean, _ := json.Marshal(redactValue(v))
+		out = string(clean)
+	} else if form, err := url.ParseQuery(string(trimmed)); err == nil && !bytes.ContainsAny(trimmed, " <{") {
+		for key := range form {
+			// The authorization code is only sent form encoded; in JSON
+			// "code" is the status code of the response envelope
+			if name := strings.ToLower(key); sensitiveFields[name] || name == "code" {
+				form.Set(key, redacted)
+			}
+		}
+		out = form.Encode()
+	} else {
+		out = string(trimmed)
+	}
+
+	if len(out) > maxRawBody {
+		out = out[:maxRawBody] + "..."
+	}
+	return out
+}
+
+// redactValue replaces the values of sensitive fields in a decoded JSON value
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if sensitiveFields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
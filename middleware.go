@@ -0,0 +1,38 @@
+package gosalla
+
+import "net/http"
+
+// Handler sends a Request and returns Salla's HTTP response
+type Handler func(req *Request) (*http.Response, error)
+
+// Middleware wraps a Handler to intercept requests and responses, e.g. to
+// add headers, sign or audit requests, or inject faults in tests. It runs
+// for every attempt of a call, after the request has been authorized and
+// rate limited, so retries pass through it again. Errors returned by a
+// middleware end the call without a retry, unless they wrap the
+// *TransportError of the next handler.
+//
+//	func auditLog(next gosalla.Handler) gosalla.Handler {
+//		return func(req *gosalla.Request) (*http.Response, error) {
+//			resp, err := next(req)
+//			audit(req.Operation, req.ResourceID, resp, err)
+//			return resp, err
+//		}
+//	}
+type Middleware func(next Handler) Handler
+
+// WithMiddleware adds middleware to the client. Middleware runs in the
+// order it was added, the first one seeing each request first.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// chain wraps handler in middleware so the first middleware runs first
+func chain(middleware []Middleware, handler Handler) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
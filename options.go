@@ -0,0 +1,128 @@
+package gosalla
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Option configures a Client created by NewClient
+type Option func(*Client)
+
+// WithToken authorizes requests with token and refreshes it through config
+// when it expires
+func WithToken(config *OAuthConfig, token *Token) Option {
+	return func(c *Client) {
+		c.oauthConfig = config
+		c.tokens = newRefreshingTokenSource(config, token)
+	}
+}
+
+// WithTokenSource authorizes requests with tokens from source, such as a
+// TokenStore backed source from OAuthConfig.StoreTokenSource
+func WithTokenSource(source TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = wrapTokenSource(source)
+	}
+}
+
+// WithOAuthConfig sets the OAuth app the client belongs to, which UserInfo
+// needs to reach the accounts API. WithToken sets it as well.
+func WithOAuthConfig(config *OAuthConfig) Option {
+	return func(c *Client) {
+		c.oauthConfig = config
+	}
+}
+
+// WithBaseURL sets a custom base URL for the API
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests. Passing
+// nil disables retries.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimiter sets the limiter used to pace requests. Passing nil
+// disables client-side rate limiting.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithLogger sets the logger the client reports to. By default the client
+// logs nothing.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithLanguage sets the Accept-Language header sent with every request,
+// e.g. "ar" or "en", which selects the language of names and messages
+func WithLanguage(lang string) Option {
+	return func(c *Client) {
+		c.language = lang
+	}
+}
+
+// RequestOption customizes a single API call
+type RequestOption func(*Request)
+
+// WithHeader sets a header on the request
+func WithHeader(key, value string) RequestOption {
+	return func(r *Request) {
+		r.Header.Set(key, value)
+	}
+}
+
+// WithQuery adds a query parameter to the request
+func WithQuery(key, value string) RequestOption {
+	return func(r *Request) {
+		query := r.URL.Query()
+		query.Add(key, value)
+		r.URL.RawQuery = query.Encode()
+	}
+}
+
+// WithAcceptLanguage overrides the client's language for the request
+func WithAcceptLanguage(lang string) RequestOption {
+	return WithHeader("Accept-Language", lang)
+}
+
+// WithIdempotencyKey sets the Idempotency-Key header of the request to key
+func WithIdempotencyKey(key string) RequestOption {
+	return WithHeader("Idempotency-Key", key)
+}
+
+// WithTimeout bounds the whole call, including retries and token refresh,
+// to d
+func WithTimeout(d time.Duration) RequestOption {
+	return func(r *Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		r.Request = r.Request.WithContext(ctx)
+		r.cancel = cancel
+	}
+}
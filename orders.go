@@ -1,7 +1,9 @@
 package gosalla
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -120,63 +122,106 @@ type OrderReservationsResponse struct {
 	Pagination *Pagination        `json:"pagination,omitempty"`
 }
 
-// List retrieves all orders with optional pagination
-func (s *OrdersService) List(opts *ListOptions) ([]Order, *Pagination, error) {
-	path := "/orders"
+// OrderListOptions filters and sorts the orders returned by List
+type OrderListOptions struct {
+	ListOptions
+	SortOptions
 	
-	// Add query parameters
-	if opts != nil {
-		path += fmt.Sprintf("?page=%d&per_page=%d", opts.Page, opts.PerPage)
-	}
+	Status        string `url:"status,omitempty"`
+	PaymentMethod string `url:"payment_method,omitempty"`
+	CustomerID    int    `url:"customer_id,omitempty"`
+	ReferenceID   string `url:"reference_id,omitempty"`
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	// FromDate and ToDate limit the orders to those created in a date range
+	FromDate time.Time `url:"from_date,omitempty"`
+	ToDate   time.Time `url:"to_date,omitempty"`
+}
+
+// List retrieves all orders with optional filters and pagination
+func (s *OrdersService) List(ctx context.Context, opts *OrderListOptions, reqOpts ...RequestOption) ([]Order, *Response, error) {
+	path, err := addOptions("/orders", opts)
 	if err != nil {
 		return nil, nil, err
 	}
 	
-	var resp OrdersListResponse
-	if err := s.client.do(req, &resp); err != nil {
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
+	if err != nil {
 		return nil, nil, err
 	}
+	req.scope = ScopeOrdersRead
+	req.Operation = "orders.list"
+	
+	var result OrdersListResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
 	
-	return resp.Data, resp.Pagination, nil
+	return result.Data, resp, nil
+}
+
+// All returns a Pager over every order matching opts, starting at the page
+// in opts
+func (s *OrdersService) All(ctx context.Context, opts *OrderListOptions, reqOpts ...RequestOption) *Pager[Order] {
+	var filters OrderListOptions
+	if opts != nil {
+		filters = *opts
+	}
+	
+	return newPager(ctx, &filters.ListOptions, func(ctx context.Context, page *ListOptions) ([]Order, *Response, error) {
+		opts := filters
+		opts.ListOptions = *page
+		return s.List(ctx, &opts, reqOpts...)
+	})
 }
 
 // Get retrieves an order by ID
-func (s *OrdersService) Get(id int) (*Order, error) {
+func (s *OrdersService) Get(ctx context.Context, id int, reqOpts ...RequestOption) (*Order, *Response, error) {
 	path := fmt.Sprintf("/orders/%d", id)
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeOrdersRead
+	req.Operation = "orders.get"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp OrderResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result OrderResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // ListReservations retrieves all current order reservations
-func (s *OrdersService) ListReservations(opts *ListOptions) ([]OrderReservation, *Pagination, error) {
-	path := "/orders/reservations"
-	
-	// Add query parameters
-	if opts != nil {
-		path += fmt.Sprintf("?page=%d&per_page=%d", opts.Page, opts.PerPage)
+func (s *OrdersService) ListReservations(ctx context.Context, opts *ListOptions, reqOpts ...RequestOption) ([]OrderReservation, *Response, error) {
+	path, err := addOptions("/orders/reservations", opts)
+	if err != nil {
+		return nil, nil, err
 	}
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
 		return nil, nil, err
 	}
+	req.scope = ScopeOrdersRead
+	req.Operation = "orders.list_reservations"
 	
-	var resp OrderReservationsResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, nil, err
+	var result OrderReservationsResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return resp.Data, resp.Pagination, nil
+	return result.Data, resp, nil
+}
+
+// AllReservations returns a Pager over every order reservation, starting at the page in opts
+func (s *OrdersService) AllReservations(ctx context.Context, opts *ListOptions, reqOpts ...RequestOption) *Pager[OrderReservation] {
+	return newPager(ctx, opts, func(ctx context.Context, opts *ListOptions) ([]OrderReservation, *Response, error) {
+		return s.ListReservations(ctx, opts, reqOpts...)
+	})
 }
@@ -0,0 +1,177 @@
+package gosalla
+
+import "context"
+
+// Pager walks the pages of a list endpoint lazily, fetching a page only
+// when the previous one has been consumed. It follows page numbers or
+// cursors, whichever the endpoint uses. Pages can be read one at a time
+// with NextPage or item by item with Items. A Pager is not safe for
+// concurrent use.
+type Pager[T any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, opts *ListOptions) ([]T, *Response, error)
+
+	next      ListOptions // the page to fetch next
+	current   ListOptions // the page buf was fetched from
+	buf       []T         // items of the current page not consumed yet
+	more      bool        // whether there are pages left to fetch
+	truncated bool        // whether the item limit cut the current page short
+	limited   bool        // whether the item limit stopped the pager before the last page
+	maxItems  int
+	fetched   int
+	pageSize  int // length of the current page before the limit cut it
+	resp      *Response
+}
+
+// newPager creates a Pager that starts at opts and fetches pages with fetch
+func newPager[T any](ctx context.Context, opts *ListOptions, fetch func(ctx context.Context, opts *ListOptions) ([]T, *Response, error)) *Pager[T] {
+	p := &Pager[T]{ctx: ctx, fetch: fetch, more: true}
+	if opts != nil {
+		p.next = *opts
+	}
+	if p.next.Page < 1 {
+		p.next.Page = 1
+	}
+	return p
+}
+
+// Limit stops the pager after n items in total. Zero means no limit.
+func (p *Pager[T]) Limit(n int) *Pager[T] {
+	p.maxItems = n
+	return p
+}
+
+// Done reports whether every item has been consumed or the item limit has
+// been reached
+func (p *Pager[T]) Done() bool {
+	return !p.more && len(p.buf) == 0
+}
+
+// Response returns the response of the most recently fetched page, or nil
+// before the first page is fetched. Its Pagination reports the totals.
+func (p *Pager[T]) Response() *Response {
+	return p.resp
+}
+
+// NextPage returns the items of the next page, or the rest of the current
+// page if Items stopped inside it. It returns nil and no error once the
+// pager is done. After an error, calling NextPage again retries the page.
+func (p *Pager[T]) NextPage() ([]T, error) {
+	if len(p.buf) == 0 {
+		if !p.more {
+			return nil, nil
+		}
+		if err := p.fill(); err != nil {
+			return nil, err
+		}
+	}
+
+	items := p.buf
+	p.buf = nil
+	return items, nil
+}
+
+// Items returns an iterator over every remaining item, fetching pages as
+// needed. With Go 1.23 or later it can be used in a range loop:
+//
+//	for product, err := range client.Products.All(ctx, nil).Items() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Breaking out of the loop stops the pager without fetching further pages;
+// ranging over Items again continues with the next item.
+func (p *Pager[T]) Items() func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		for {
+			if len(p.buf) == 0 {
+				if !p.more {
+					return
+				}
+				if err := p.fill(); err != nil {
+					var zero T
+					yield(zero, err)
+					return
+				}
+				continue
+			}
+
+			item := p.buf[0]
+			p.buf = p.buf[1:]
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns every remaining item
+func (p *Pager[T]) Collect() ([]T, error) {
+	var all []T
+	for !p.Done() {
+		items, err := p.NextPage()
+		if err != nil {
+			return all, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// Checkpoint returns the list options to resume from later, e.g. after a
+// crash or when a job is split over several runs. It returns nil when
+// there is nothing left to fetch. If the pager stopped inside a page, the
+// checkpoint points at that page, so its items are delivered again when
+// resuming.
+func (p *Pager[T]) Checkpoint() *ListOptions {
+	var resume ListOptions
+	switch {
+	case len(p.buf) > 0 || p.truncated:
+		resume = p.current
+	case p.more || p.limited:
+		resume = p.next
+	default:
+		return nil
+	}
+	return &resume
+}
+
+// fill fetches the next page into the buffer and advances the pager
+func (p *Pager[T]) fill() error {
+	current := p.next
+	items, resp, err := p.fetch(p.ctx, &current)
+	if err != nil {
+		return err
+	}
+	p.resp = resp
+	p.current = current
+	p.pageSize = len(items)
+
+	// Follow whichever form of pagination the endpoint uses
+	switch pagination := resp.Pagination; {
+	case len(items) == 0:
+		p.more = false
+	case pagination.NextCursor() != "":
+		p.next.Cursor = pagination.NextCursor()
+	case pagination.NextPage() != 0:
+		p.next.Page = pagination.NextPage()
+	default:
+		p.more = false
+	}
+
+	// Reaching the limit stops the pager, but unlike running out of pages
+	// it leaves the rest of the list to resume from
+	if p.maxItems > 0 && p.fetched+len(items) >= p.maxItems {
+		remaining := p.maxItems - p.fetched
+		p.truncated = len(items) > remaining
+		p.limited = p.more
+		items = items[:remaining]
+		p.more = false
+	}
+	p.fetched += len(items)
+
+	p.buf = items
+	return nil
+}
@@ -0,0 +1,353 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakePages serves items 1..total in pages of perPage and records the pages requested
+func fakePages(total, perPage int, requested *[]int) func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+	lastPage := (total + perPage - 1) / perPage
+	return func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+		*requested = append(*requested, opts.Page)
+
+		var items []int
+		for i := (opts.Page-1)*perPage + 1; i <= total && i <= opts.Page*perPage; i++ {
+			items = append(items, i)
+		}
+		return items, &Response{Pagination: &Pagination{CurrentPage: opts.Page, LastPage: lastPage, Total: total}}, nil
+	}
+}
+
+func TestPagerItems(t *testing.T) {
+	var requested []int
+	pager := newPager(context.Background(), nil, fakePages(5, 2, &requested))
+
+	var got []int
+	pager.Items()(func(item int, err error) bool {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got = append(got, item)
+		return true
+	})
+
+	if !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) || !reflect.DeepEqual(requested, []int{1, 2, 3}) {
+		t.Errorf("Unexpected items %v from pages %v", got, requested)
+	}
+
+	if !pager.Done() || pager.Checkpoint() != nil {
+		t.Error("Expected pager to be done without a checkpoint")
+	}
+}
+
+func TestPagerEarlyTerminationAndCheckpoint(t *testing.T) {
+	var requested []int
+	pager := newPager(context.Background(), &ListOptions{PerPage: 2}, fakePages(6, 2, &requested))
+
+	// Stop inside the second page
+	var got []int
+	pager.Items()(func(item int, err error) bool {
+		got = append(got, item)
+		return item < 3
+	})
+
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) || len(requested) != 2 {
+		t.Fatalf("Expected to stop after item 3 without fetching more, got %v from pages %v", got, requested)
+	}
+
+	checkpoint := pager.Checkpoint()
+	if checkpoint == nil || checkpoint.Page != 2 || checkpoint.PerPage != 2 {
+		t.Fatalf("Expected checkpoint at page 2, got %+v", checkpoint)
+	}
+
+	// Resuming from the checkpoint redelivers the interrupted page
+	resumed, err := newPager(context.Background(), checkpoint, fakePages(6, 2, &requested)).Collect()
+	if err != nil || !reflect.DeepEqual(resumed, []int{3, 4, 5, 6}) {
+		t.Errorf("Expected items 3-6 after resuming, got %v (%v)", resumed, err)
+	}
+}
+
+func TestPagerLimit(t *testing.T) {
+	var requested []int
+	pager := newPager(context.Background(), nil, fakePages(10, 3, &requested)).Limit(4)
+
+	items, err := pager.Collect()
+	if err != nil || !reflect.DeepEqual(items, []int{1, 2, 3, 4}) {
+		t.Fatalf("Expected 4 items, got %v (%v)", items, err)
+	}
+
+	if len(requested) != 2 {
+		t.Errorf("Expected 2 pages to be fetched, got %v", requested)
+	}
+
+	if checkpoint := pager.Checkpoint(); checkpoint == nil || checkpoint.Page != 2 {
+		t.Errorf("Expected checkpoint at the cut page 2, got %+v", checkpoint)
+	}
+}
+
+func TestPagerLimitOnPageBoundary(t *testing.T) {
+	var requested []int
+	pager := newPager(context.Background(), nil, fakePages(10, 3, &requested)).Limit(3)
+
+	items, err := pager.Collect()
+	if err != nil || !reflect.DeepEqual(items, []int{1, 2, 3}) {
+		t.Fatalf("Expected 3 items, got %v (%v)", items, err)
+	}
+
+	if len(requested) != 1 {
+		t.Errorf("Expected 1 page to be fetched, got %v", requested)
+	}
+
+	// The limit is not the end of the list, so the checkpoint points at the
+	// next page
+	if checkpoint := pager.Checkpoint(); checkpoint == nil || checkpoint.Page != 2 {
+		t.Errorf("Expected checkpoint at page 2, got %+v", checkpoint)
+	}
+
+	// A limit reached on the last page leaves nothing to resume
+	pager = newPager(context.Background(), nil, fakePages(6, 3, &requested)).Limit(6)
+	if _, err := pager.Collect(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if checkpoint := pager.Checkpoint(); checkpoint != nil {
+		t.Errorf("Expected no checkpoint, got %+v", checkpoint)
+	}
+}
+
+func TestPagerRetriesFailedPage(t *testing.T) {
+	var requested []int
+	pages := fakePages(4, 2, &requested)
+	failures := 1
+	pager := newPager(context.Background(), nil, func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+		if opts.Page == 2 && failures > 0 {
+			failures--
+			return nil, nil, errors.New("boom")
+		}
+		return pages(ctx, opts)
+	})
+
+	if _, err := pager.NextPage(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := pager.NextPage(); err == nil {
+		t.Fatal("Expected error for page 2")
+	}
+
+	items, err := pager.NextPage()
+	if err != nil || !reflect.DeepEqual(items, []int{3, 4}) {
+		t.Errorf("Expected page 2 on retry, got %v (%v)", items, err)
+	}
+}
+
+func TestProductsAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `{"success": true, "data": [{"id": %d}], "pagination": {"current_page": %d, "last_page": 3}}`, page, page)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}), WithBaseURL(server.URL))
+
+	products, err := client.Products.All(context.Background(), nil).Collect()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(products) != 3 || products[2].ID != 3 {
+		t.Errorf("Expected products from 3 pages, got %+v", products)
+	}
+}
+
+// syncPages wraps fakePages for concurrent use
+func syncPages(total, perPage int) func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+	var mu sync.Mutex
+	var requested []int
+	pages := fakePages(total, perPage, &requested)
+	return func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return pages(ctx, opts)
+	}
+}
+
+func TestPagerFetchAll(t *testing.T) {
+	var last Progress
+	pager := newPager(context.Background(), nil, syncPages(13, 2))
+
+	items, err := pager.FetchAll(&BulkOptions{
+		Concurrency: 3,
+		OnProgress: func(p Progress) {
+			last = p
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(items) != 13 {
+		t.Fatalf("Expected 13 items, got %v", items)
+	}
+	for i, item := range items {
+		if item != i+1 {
+			t.Fatalf("Expected items in order, got %v", items)
+		}
+	}
+
+	if last.Pages != 7 || last.TotalPages != 7 || last.Items != 13 || last.TotalItems != 13 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+
+	limited, _ := newPager(context.Background(), nil, syncPages(13, 2)).Limit(5).FetchAll(nil)
+	if !reflect.DeepEqual(limited, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected 5 items with a limit, got %v", limited)
+	}
+}
+
+func TestPagerFetchAllError(t *testing.T) {
+	pages := syncPages(10, 2)
+	pager := newPager(context.Background(), nil, func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+		if opts.Page == 3 {
+			return nil, nil, errors.New("boom")
+		}
+		return pages(ctx, opts)
+	})
+
+	items, err := pager.FetchAll(&BulkOptions{Concurrency: 1})
+	if err == nil {
+		t.Fatal("Expected error for page 3")
+	}
+
+	if !reflect.DeepEqual(items, []int{1, 2, 3, 4}) {
+		t.Errorf("Expected the pages before the failure, got %v", items)
+	}
+
+	if checkpoint := pager.Checkpoint(); checkpoint == nil || checkpoint.Page != 3 {
+		t.Errorf("Expected checkpoint at page 3, got %+v", checkpoint)
+	}
+}
+
+func TestPagerFetchAllResumeWithLimit(t *testing.T) {
+	pages := syncPages(20, 3)
+	failures := 1
+	pager := newPager(context.Background(), nil, func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+		if opts.Page == 3 && failures > 0 {
+			failures--
+			return nil, nil, errors.New("boom")
+		}
+		return pages(ctx, opts)
+	}).Limit(7)
+
+	items, err := pager.FetchAll(&BulkOptions{Concurrency: 1})
+	if err == nil || !reflect.DeepEqual(items, []int{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("Expected the pages before the failure, got %v (%v)", items, err)
+	}
+
+	// Resuming counts the items already returned against the limit
+	rest, err := pager.FetchAll(nil)
+	if err != nil || !reflect.DeepEqual(rest, []int{7}) {
+		t.Errorf("Expected the last item within the limit, got %v (%v)", rest, err)
+	}
+
+	// A partly read page still counts as a full page
+	pager = newPager(context.Background(), nil, syncPages(500, 50)).Limit(100)
+	read := 0
+	pager.Items()(func(item int, err error) bool {
+		read++
+		return read < 10
+	})
+
+	rest, err = pager.FetchAll(nil)
+	if err != nil || len(rest) != 90 || rest[0] != 11 || rest[89] != 100 {
+		t.Errorf("Expected items 11-100 after reading 10, got %d items (%v)", len(rest), err)
+	}
+}
+
+func TestPagerStream(t *testing.T) {
+	pager := newPager(context.Background(), nil, syncPages(9, 2))
+
+	seen := make(map[int]bool)
+	count := 0
+	for result := range pager.Stream(&BulkOptions{Concurrency: 4}) {
+		if result.Err != nil {
+			t.Fatalf("Unexpected error: %v", result.Err)
+		}
+		seen[result.Page] = true
+		count += len(result.Items)
+	}
+
+	if len(seen) != 5 || count != 9 {
+		t.Errorf("Expected 9 items from 5 pages, got %d from %v", count, seen)
+	}
+}
+
+func TestProductsAllWithCursor(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+
+		switch cursor {
+		case "":
+			fmt.Fprintf(w, `{"success": true, "data": [{"id": 1}], "cursor": {"current": null, "previous": null, "next": "%s/products?cursor=abc", "count": 1}}`, "https://api.salla.dev/admin/v2")
+		case "abc":
+			fmt.Fprint(w, `{"success": true, "data": [{"id": 2}], "pagination": {"cursor": {"current": "abc", "previous": null, "next": 42, "count": 1}}}`)
+		case "42":
+			fmt.Fprint(w, `{"success": true, "data": [{"id": 3}], "cursor": {"current": 42, "previous": "abc", "next": null, "count": 1}}`)
+		default:
+			t.Errorf("Unexpected cursor %q", cursor)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}), WithBaseURL(server.URL))
+
+	pager := client.Products.All(context.Background(), nil)
+	products, err := pager.Collect()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(products) != 3 || products[2].ID != 3 {
+		t.Errorf("Expected products from 3 pages, got %+v", products)
+	}
+
+	if !reflect.DeepEqual(cursors, []string{"", "abc", "42"}) {
+		t.Errorf("Expected the pager to follow the cursors, got %q", cursors)
+	}
+
+	if previous := pager.Response().Pagination.PreviousCursor(); previous != "abc" {
+		t.Errorf("Expected previous cursor abc, got %q", previous)
+	}
+}
+
+func TestPagerFetchAllWithCursor(t *testing.T) {
+	var requested []string
+	pager := newPager(context.Background(), nil, func(ctx context.Context, opts *ListOptions) ([]int, *Response, error) {
+		requested = append(requested, opts.Cursor)
+
+		page := len(requested)
+		cursor := &Cursor{Next: strconv.Itoa(page + 1)}
+		if page == 3 {
+			cursor.Next = ""
+		}
+		return []int{page}, &Response{Pagination: &Pagination{Cursor: cursor}}, nil
+	})
+
+	items, err := pager.FetchAll(&BulkOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(items, []int{1, 2, 3}) || !reflect.DeepEqual(requested, []string{"", "2", "3"}) {
+		t.Errorf("Expected pages to be followed in turn, got %v from cursors %q", items, requested)
+	}
+}
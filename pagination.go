@@ -1,13 +1,70 @@
 package gosalla
 
-// Pagination represents pagination metadata from API responses
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Pagination represents pagination metadata from API responses. Endpoints
+// that use cursor pagination set Cursor instead of the page numbers.
 type Pagination struct {
-	CurrentPage int `json:"current_page"`
-	From        int `json:"from"`
-	LastPage    int `json:"last_page"`
-	PerPage     int `json:"per_page"`
-	To          int `json:"to"`
-	Total       int `json:"total"`
+	CurrentPage int     `json:"current_page"`
+	From        int     `json:"from"`
+	LastPage    int     `json:"last_page"`
+	PerPage     int     `json:"per_page"`
+	To          int     `json:"to"`
+	Total       int     `json:"total"`
+	Cursor      *Cursor `json:"cursor,omitempty"`
+}
+
+// Cursor represents cursor pagination metadata. Next and Previous are
+// either cursors or links carrying a cursor query parameter, and are empty
+// at the ends of the list.
+type Cursor struct {
+	Current  string `json:"current"`
+	Previous string `json:"previous"`
+	Next     string `json:"next"`
+	Count    int    `json:"count"`
+}
+
+// UnmarshalJSON decodes a cursor, accepting numbers and null for its values
+func (c *Cursor) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Current  interface{} `json:"current"`
+		Previous interface{} `json:"previous"`
+		Next     interface{} `json:"next"`
+		Count    int         `json:"count"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.Current = cursorString(raw.Current)
+	c.Previous = cursorString(raw.Previous)
+	c.Next = cursorString(raw.Next)
+	c.Count = raw.Count
+	return nil
+}
+
+// cursorString formats a decoded cursor value, which may be null
+func cursorString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// cursorValue extracts the cursor from a cursor or a link carrying one
+func cursorValue(s string) string {
+	if u, err := url.Parse(s); err == nil && u.IsAbs() {
+		return u.Query().Get("cursor")
+	}
+	return s
 }
 
 // HasNextPage checks if there are more pages available
@@ -15,17 +72,39 @@ func (p *Pagination) HasNextPage() bool {
 	if p == nil {
 		return false
 	}
+	if p.Cursor != nil {
+		return p.NextCursor() != ""
+	}
 	return p.CurrentPage < p.LastPage
 }
 
 // NextPage returns the next page number, or 0 if there are no more pages
+// or the endpoint uses cursor pagination
 func (p *Pagination) NextPage() int {
-	if !p.HasNextPage() {
+	if !p.HasNextPage() || p.Cursor != nil {
 		return 0
 	}
 	return p.CurrentPage + 1
 }
 
+// NextCursor returns the cursor of the next page, or "" if there are no
+// more pages or the endpoint uses page numbers
+func (p *Pagination) NextCursor() string {
+	if p == nil || p.Cursor == nil {
+		return ""
+	}
+	return cursorValue(p.Cursor.Next)
+}
+
+// PreviousCursor returns the cursor of the previous page, or "" if there
+// is none
+func (p *Pagination) PreviousCursor() string {
+	if p == nil || p.Cursor == nil {
+		return ""
+	}
+	return cursorValue(p.Cursor.Previous)
+}
+
 // HasPreviousPage checks if there is a previous page
 func (p *Pagination) HasPreviousPage() bool {
 	if p == nil {
@@ -42,8 +121,30 @@ func (p *Pagination) PreviousPage() int {
 	return p.CurrentPage - 1
 }
 
-// ListOptions represents common options for list endpoints
+// ListOptions represents common options for list endpoints. Zero values
+// are not sent.
 type ListOptions struct {
 	Page    int `url:"page,omitempty"`
 	PerPage int `url:"per_page,omitempty"`
+	
+	// Cursor requests the page at a cursor taken from
+	// Pagination.NextCursor, on endpoints that use cursor pagination.
+	// Page is ignored when Cursor is set.
+	Cursor string `url:"cursor,omitempty"`
+}
+
+// SortDirection is the order in which list results are sorted
+type SortDirection string
+
+// Sort directions
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+// SortOptions represents sorting options for list endpoints
+type SortOptions struct {
+	// SortBy is the field to sort by, e.g. "created_at"
+	SortBy        string        `url:"sort_by,omitempty"`
+	SortDirection SortDirection `url:"sort_direction,omitempty"`
 }
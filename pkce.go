@@ -0,0 +1,53 @@
+package gosalla
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+)
+
+// AuthCodeOption adds parameters to the authorization URL or the code
+// exchange request, such as the PKCE challenge and verifier
+type AuthCodeOption func(params url.Values)
+
+// GenerateVerifier returns a new random PKCE code verifier as defined in
+// RFC 7636. Keep it until the code is exchanged, then pass it with
+// VerifierOption.
+func GenerateVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// S256Challenge returns the S256 code challenge for verifier
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyS256Challenge reports whether challenge is the S256 code challenge
+// for verifier, e.g. in a stub authorization server
+func VerifyS256Challenge(verifier, challenge string) bool {
+	return subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(challenge)) == 1
+}
+
+// S256ChallengeOption adds the S256 code challenge for verifier to the
+// authorization URL
+func S256ChallengeOption(verifier string) AuthCodeOption {
+	return func(params url.Values) {
+		params.Set("code_challenge", S256Challenge(verifier))
+		params.Set("code_challenge_method", "S256")
+	}
+}
+
+// VerifierOption adds the PKCE code verifier to the code exchange request
+func VerifierOption(verifier string) AuthCodeOption {
+	return func(params url.Values) {
+		params.Set("code_verifier", verifier)
+	}
+}
@@ -0,0 +1,217 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientPool manages one Client per merchant for apps installed by many
+// merchants. Clients are created on first use from tokens in a TokenStore,
+// share a single http.Client and connection pool, refresh and persist their
+// own tokens, and are rate limited independently.
+type ClientPool struct {
+	config     *OAuthConfig
+	store      TokenStore
+	httpClient *http.Client
+	opts       ClientPoolOptions
+
+	mu      sync.Mutex
+	clients map[int]*pooledClient
+	done    chan struct{}
+	closed  bool
+}
+
+// ClientPoolOptions configures a ClientPool
+type ClientPoolOptions struct {
+	// HTTPClient is shared by every client in the pool. Defaults to an
+	// http.Client with a 30 second timeout.
+	HTTPClient *http.Client
+
+	// IdleTimeout evicts clients that have not been requested for this
+	// long. Zero keeps clients until they are evicted explicitly.
+	IdleTimeout time.Duration
+
+	// RateLimit and RateWindow set a fixed per-merchant request budget.
+	// A zero RateLimit lets each client learn its quota from Salla.
+	RateLimit  int
+	RateWindow time.Duration
+
+	// OnReauthorizationRequired, if set, is called when a merchant's token
+	// can no longer be refreshed, so the app can flag the merchant for
+	// reinstallation
+	OnReauthorizationRequired func(merchantID int, err error)
+
+	// ClientOptions are applied to every newly created client, after the
+	// pool's own settings
+	ClientOptions []Option
+
+	// Configure, if set, is called for every newly created client
+	Configure func(merchantID int, client *Client)
+}
+
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// NewClientPool creates a pool that builds clients from config and the
+// tokens in store. opts may be nil.
+func NewClientPool(config *OAuthConfig, store TokenStore, opts *ClientPoolOptions) *ClientPool {
+	p := &ClientPool{
+		config:  config,
+		store:   store,
+		clients: make(map[int]*pooledClient),
+		done:    make(chan struct{}),
+	}
+
+	if opts != nil {
+		p.opts = *opts
+	}
+
+	p.httpClient = p.opts.HTTPClient
+	if p.httpClient == nil {
+		p.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	if p.opts.IdleTimeout > 0 {
+		go p.evictLoop(p.opts.IdleTimeout)
+	}
+
+	return p
+}
+
+// For returns the client for merchantID, creating it on first use. The
+// merchant's token is loaded from the store when the first request is made,
+// so a missing token surfaces as an error from that request. A client whose
+// merchant has no token or must reauthorize is dropped from the pool, so
+// the next call to For starts over from the store.
+func (p *ClientPool) For(merchantID int) *Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pc, ok := p.clients[merchantID]; ok {
+		pc.lastUsed = time.Now()
+		return pc.client
+	}
+
+	var client *Client
+	store := &pooledStore{TokenStore: p.store, onMissing: func() {
+		p.evictClient(merchantID, client)
+	}}
+
+	opts := append([]Option{
+		WithTokenSource(p.config.StoreTokenSource(store, merchantID)),
+		WithOAuthConfig(p.config),
+		WithMerchant(merchantID),
+		WithHTTPClient(p.httpClient),
+		WithRateLimiter(NewRateLimiter(p.opts.RateLimit, p.opts.RateWindow)),
+	}, p.opts.ClientOptions...)
+	client = NewClient(opts...)
+
+	onReauth := p.opts.OnReauthorizationRequired
+	client.OnReauthorizationRequired(func(err error) {
+		p.evictClient(merchantID, client)
+		if onReauth != nil {
+			onReauth(merchantID, err)
+		}
+	})
+
+	if p.opts.Configure != nil {
+		p.opts.Configure(merchantID, client)
+	}
+
+	p.clients[merchantID] = &pooledClient{client: client, lastUsed: time.Now()}
+	return client
+}
+
+// Evict removes the client for merchantID, e.g. after the app was
+// uninstalled. The next call to For creates a fresh client.
+func (p *ClientPool) Evict(merchantID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, merchantID)
+}
+
+// evictClient removes client if it is still the one held for merchantID
+func (p *ClientPool) evictClient(merchantID int, client *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pc, ok := p.clients[merchantID]; ok && pc.client == client {
+		delete(p.clients, merchantID)
+	}
+}
+
+// EvictIdle removes clients that have not been requested for longer than
+// maxIdle and returns how many were removed
+func (p *ClientPool) EvictIdle(maxIdle time.Duration) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxIdle)
+	evicted := 0
+	for merchantID, pc := range p.clients {
+		if pc.lastUsed.Before(cutoff) {
+			delete(p.clients, merchantID)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Len returns the number of clients currently held by the pool
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
+
+// Close stops the background eviction of idle clients
+func (p *ClientPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+}
+
+// pooledStore is the TokenStore of a pooled client. It reports merchants
+// without a token, so their clients are not kept in the pool.
+type pooledStore struct {
+	TokenStore
+	onMissing func()
+}
+
+// Get implements TokenStore
+func (s *pooledStore) Get(ctx context.Context, merchantID int) (*Token, error) {
+	token, err := s.TokenStore.Get(ctx, merchantID)
+	if errors.Is(err, ErrTokenNotFound) {
+		s.onMissing()
+	}
+	return token, err
+}
+
+// evictLoop periodically evicts idle clients until the pool is closed
+func (p *ClientPool) evictLoop(idleTimeout time.Duration) {
+	interval := idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.EvictIdle(idleTimeout)
+		}
+	}
+}
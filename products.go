@@ -1,7 +1,9 @@
 package gosalla
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -96,117 +98,169 @@ type UpdateProductRequest struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// List retrieves all products with optional pagination
-func (s *ProductsService) List(opts *ListOptions) ([]Product, *Pagination, error) {
-	path := "/products"
+// ProductListOptions filters and sorts the products returned by List
+type ProductListOptions struct {
+	ListOptions
+	SortOptions
 	
-	// Add query parameters
-	if opts != nil {
-		path += fmt.Sprintf("?page=%d&per_page=%d", opts.Page, opts.PerPage)
-	}
+	Status   string `url:"status,omitempty"`
+	Category int    `url:"category,omitempty"`
+	Brand    int    `url:"brand,omitempty"`
+	Keyword  string `url:"keyword,omitempty"`
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	// PriceFrom and PriceTo limit the products to a price range
+	PriceFrom float64 `url:"price_from,omitempty"`
+	PriceTo   float64 `url:"price_to,omitempty"`
+}
+
+// List retrieves all products with optional filters and pagination
+func (s *ProductsService) List(ctx context.Context, opts *ProductListOptions, reqOpts ...RequestOption) ([]Product, *Response, error) {
+	path, err := addOptions("/products", opts)
 	if err != nil {
 		return nil, nil, err
 	}
 	
-	var resp ProductsListResponse
-	if err := s.client.do(req, &resp); err != nil {
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
+	if err != nil {
 		return nil, nil, err
 	}
+	req.scope = ScopeProductsRead
+	req.Operation = "products.list"
+	
+	var result ProductsListResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
+	
+	return result.Data, resp, nil
+}
+
+// All returns a Pager over every product matching opts, starting at the
+// page in opts
+func (s *ProductsService) All(ctx context.Context, opts *ProductListOptions, reqOpts ...RequestOption) *Pager[Product] {
+	var filters ProductListOptions
+	if opts != nil {
+		filters = *opts
+	}
 	
-	return resp.Data, resp.Pagination, nil
+	return newPager(ctx, &filters.ListOptions, func(ctx context.Context, page *ListOptions) ([]Product, *Response, error) {
+		opts := filters
+		opts.ListOptions = *page
+		return s.List(ctx, &opts, reqOpts...)
+	})
 }
 
 // Get retrieves a product by ID
-func (s *ProductsService) Get(id int) (*Product, error) {
+func (s *ProductsService) Get(ctx context.Context, id int, reqOpts ...RequestOption) (*Product, *Response, error) {
 	path := fmt.Sprintf("/products/%d", id)
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeProductsRead
+	req.Operation = "products.get"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp ProductResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result ProductResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // GetBySKU retrieves a product by SKU
-func (s *ProductsService) GetBySKU(sku string) (*Product, error) {
+func (s *ProductsService) GetBySKU(ctx context.Context, sku string, reqOpts ...RequestOption) (*Product, *Response, error) {
 	path := fmt.Sprintf("/products/sku/%s", sku)
 	
-	req, err := s.client.newRequest("GET", path, nil)
+	req, err := s.client.newRequest(ctx, "GET", path, nil, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeProductsRead
+	req.Operation = "products.get_by_sku"
+	req.ResourceID = sku
 	
-	var resp ProductResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result ProductResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Create creates a new product
-func (s *ProductsService) Create(product *CreateProductRequest) (*Product, error) {
+func (s *ProductsService) Create(ctx context.Context, product *CreateProductRequest, reqOpts ...RequestOption) (*Product, *Response, error) {
 	path := "/products"
 	
-	req, err := s.client.newRequest("POST", path, product)
+	req, err := s.client.newRequest(ctx, "POST", path, product, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeProductsReadWrite
+	req.Operation = "products.create"
 	
-	var resp ProductResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result ProductResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Update updates an existing product
-func (s *ProductsService) Update(id int, product *UpdateProductRequest) (*Product, error) {
+func (s *ProductsService) Update(ctx context.Context, id int, product *UpdateProductRequest, reqOpts ...RequestOption) (*Product, *Response, error) {
 	path := fmt.Sprintf("/products/%d", id)
 	
-	req, err := s.client.newRequest("PUT", path, product)
+	req, err := s.client.newRequest(ctx, "PUT", path, product, reqOpts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	req.scope = ScopeProductsReadWrite
+	req.Operation = "products.update"
+	req.ResourceID = strconv.Itoa(id)
 	
-	var resp ProductResponse
-	if err := s.client.do(req, &resp); err != nil {
-		return nil, err
+	var result ProductResponse
+	resp, err := s.client.do(req, &result)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return &resp.Data, nil
+	return &result.Data, resp, nil
 }
 
 // Delete deletes a product
-func (s *ProductsService) Delete(id int) error {
+func (s *ProductsService) Delete(ctx context.Context, id int, reqOpts ...RequestOption) (*Response, error) {
 	path := fmt.Sprintf("/products/%d", id)
 	
-	req, err := s.client.newRequest("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, "DELETE", path, nil, reqOpts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	req.scope = ScopeProductsReadWrite
+	req.Operation = "products.delete"
+	req.ResourceID = strconv.Itoa(id)
 	
 	return s.client.do(req, nil)
 }
 
 // ChangeStatus changes the status of a product
-func (s *ProductsService) ChangeStatus(id int, status string) error {
+func (s *ProductsService) ChangeStatus(ctx context.Context, id int, status string, reqOpts ...RequestOption) (*Response, error) {
 	path := fmt.Sprintf("/products/%d/status", id)
 	
 	body := map[string]string{"status": status}
-	req, err := s.client.newRequest("POST", path, body)
+	req, err := s.client.newRequest(ctx, "POST", path, body, reqOpts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	req.scope = ScopeProductsReadWrite
+	req.Operation = "products.change_status"
+	req.ResourceID = strconv.Itoa(id)
 	
 	return s.client.do(req, nil)
 }
@@ -0,0 +1,128 @@
+package gosalla
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryDateLayout is the format of dates in query parameters
+const queryDateLayout = "2006-01-02"
+
+// addOptions encodes the fields of the options struct opts as query
+// parameters and appends them to path. Fields are named by their url tag
+// and skipped without one; the omitempty option skips zero values.
+// Embedded structs are flattened, slices add one parameter per element,
+// and time.Time values are sent as dates.
+func addOptions(path string, opts interface{}) (string, error) {
+	v := reflect.ValueOf(opts)
+	if opts == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return path, nil
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return path, fmt.Errorf("failed to parse path: %w", err)
+	}
+
+	query := u.Query()
+	if err := encodeQuery(query, reflect.Indirect(v)); err != nil {
+		return path, err
+	}
+
+	// Page is ignored when Cursor is set
+	if query.Get("cursor") != "" {
+		query.Del("page")
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// encodeQuery adds the tagged fields of the struct v to query
+func encodeQuery(query url.Values, v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("query options must be a struct, got %s", v.Type())
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		tag := field.Tag.Get("url")
+		if tag == "" && field.Anonymous {
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				if err := encodeQuery(query, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if options == "omitempty" && value.IsZero() {
+			continue
+		}
+
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			for j := 0; j < value.Len(); j++ {
+				s, err := queryValue(value.Index(j))
+				if err != nil {
+					return fmt.Errorf("failed to encode %s: %w", field.Name, err)
+				}
+				query.Add(name, s)
+			}
+			continue
+		}
+
+		s, err := queryValue(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", field.Name, err)
+		}
+		query.Set(name, s)
+	}
+
+	return nil
+}
+
+// queryValue formats a single query parameter value
+func queryValue(v reflect.Value) (string, error) {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(queryDateLayout), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported query type %s", v.Type())
+	}
+}
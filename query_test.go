@@ -0,0 +1,91 @@
+package gosalla
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAddOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts interface{}
+		want string
+	}{
+		{"nil", (*OrderListOptions)(nil), "/orders"},
+		{"zero values", &OrderListOptions{}, "/orders"},
+		{"pagination", &ListOptions{Page: 2, PerPage: 50}, "/orders?page=2&per_page=50"},
+		{"cursor replaces page", &ListOptions{Page: 2, Cursor: "a b"}, "/orders?cursor=a+b"},
+		{
+			"filters and sorting",
+			&OrderListOptions{
+				ListOptions:   ListOptions{PerPage: 10},
+				SortOptions:   SortOptions{SortBy: "created_at", SortDirection: SortDescending},
+				Status:        "completed",
+				PaymentMethod: "credit_card",
+				FromDate:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			},
+			"/orders?from_date=2024-01-01&payment_method=credit_card&per_page=10&sort_by=created_at&sort_direction=desc&status=completed",
+		},
+		{"floats", &ProductListOptions{PriceFrom: 9.5, PriceTo: 100}, "/orders?price_from=9.5&price_to=100"},
+		{
+			"slices and pointers",
+			&struct {
+				IDs    []int `url:"ids[]"`
+				Hidden *bool `url:"hidden,omitempty"`
+				Skip   string
+			}{IDs: []int{1, 2}, Hidden: new(bool), Skip: "x"},
+			"/orders?hidden=false&ids%5B%5D=1&ids%5B%5D=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addOptions("/orders", tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := addOptions("/orders", &struct {
+		Filter map[string]string `url:"filter"`
+	}{Filter: map[string]string{}}); err == nil {
+		t.Error("Expected error for an unsupported type")
+	}
+}
+
+func TestProductsAllKeepsFilters(t *testing.T) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		if r.URL.Query().Get("page") == "1" {
+			w.Write([]byte(`{"success": true, "data": [{"id": 1}], "pagination": {"current_page": 1, "last_page": 2}}`))
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": [{"id": 2}], "pagination": {"current_page": 2, "last_page": 2}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}), WithBaseURL(server.URL))
+
+	products, err := client.Products.All(context.Background(), &ProductListOptions{Keyword: "shirt", Category: 7}).Collect()
+	if err != nil || len(products) != 2 {
+		t.Fatalf("Expected 2 products, got %+v (%v)", products, err)
+	}
+
+	for i, query := range queries {
+		if query.Get("keyword") != "shirt" || query.Get("category") != "7" {
+			t.Errorf("Expected filters on page %d, got %v", i+1, query)
+		}
+	}
+	if queries[1].Get("page") != "2" {
+		t.Errorf("Expected page 2 to be requested, got %v", queries[1])
+	}
+}
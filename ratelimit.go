@@ -0,0 +1,164 @@
+package gosalla
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limit response headers sent by Salla
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+)
+
+// Rate represents the API quota reported by Salla in its rate limit headers
+type Rate struct {
+	Limit     int       // requests allowed per window
+	Remaining int       // requests left in the current window
+	Reset     time.Time // when the current window resets, zero if unknown
+}
+
+// parseRate extracts the rate limit state from response headers. It returns
+// false if the response carried no rate limit headers.
+func parseRate(header http.Header, now time.Time) (Rate, bool) {
+	var rate Rate
+
+	limit, err := strconv.Atoi(header.Get(headerRateLimit))
+	if err != nil {
+		return rate, false
+	}
+	rate.Limit = limit
+
+	if remaining, err := strconv.Atoi(header.Get(headerRateRemaining)); err == nil {
+		rate.Remaining = remaining
+	} else {
+		rate.Remaining = limit
+	}
+
+	// The reset header is either a Unix timestamp or a number of seconds
+	if reset, err := strconv.ParseInt(header.Get(headerRateReset), 10, 64); err == nil && reset > 0 {
+		if reset > 1e9 {
+			rate.Reset = time.Unix(reset, 0)
+		} else {
+			rate.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	return rate, true
+}
+
+// RateLimiter is a token-bucket limiter that paces outgoing requests. Its
+// capacity adapts to the limits Salla reports, and callers block until a
+// request may be sent instead of running into 429 responses.
+type RateLimiter struct {
+	mu         sync.Mutex
+	window     time.Duration
+	capacity   float64 // 0 until the limit is known
+	tokens     float64
+	last       time.Time
+	pauseUntil time.Time
+}
+
+// NewRateLimiter creates a limiter allowing limit requests per window. A limit
+// of 0 lets the limiter learn the quota from Salla's response headers.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &RateLimiter{
+		window:   window,
+		capacity: float64(limit),
+		tokens:   float64(limit),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+
+	var wait time.Duration
+	if now.Before(l.pauseUntil) {
+		wait = l.pauseUntil.Sub(now)
+	}
+
+	reserved := false
+	if l.capacity > 0 {
+		// Reserve a token up front; a negative balance is the queue of
+		// callers waiting for the bucket to refill
+		l.tokens--
+		reserved = true
+		if l.tokens < 0 {
+			if d := time.Duration(-l.tokens / l.capacity * float64(l.window)); d > wait {
+				wait = d
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, wait); err != nil {
+		if reserved {
+			l.mu.Lock()
+			l.tokens++
+			l.mu.Unlock()
+		}
+		return err
+	}
+	return nil
+}
+
+// observe adapts the limiter to the quota reported by the server
+func (l *RateLimiter) observe(rate Rate, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(now)
+
+	if rate.Limit > 0 {
+		if l.capacity == 0 {
+			l.tokens = float64(rate.Remaining)
+		}
+		l.capacity = float64(rate.Limit)
+	}
+
+	// The server's count is authoritative when it is lower than ours
+	if remaining := float64(rate.Remaining); remaining < l.tokens {
+		l.tokens = remaining
+	}
+
+	if rate.Remaining <= 0 && rate.Reset.After(now) {
+		l.pause(rate.Reset)
+	}
+}
+
+// holdUntil holds back all requests until t, e.g. after a 429 response
+func (l *RateLimiter) holdUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pause(t)
+}
+
+// pause holds back all requests until t
+func (l *RateLimiter) pause(t time.Time) {
+	if t.After(l.pauseUntil) {
+		l.pauseUntil = t
+	}
+}
+
+// refill adds the tokens accrued since the last update
+func (l *RateLimiter) refill(now time.Time) {
+	if l.capacity > 0 {
+		elapsed := now.Sub(l.last)
+		l.tokens += elapsed.Seconds() / l.window.Seconds() * l.capacity
+		if l.tokens > l.capacity {
+			l.tokens = l.capacity
+		}
+	}
+	l.last = now
+}
@@ -0,0 +1,134 @@
+package gosalla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NewRequest creates a request for an endpoint the SDK does not model.
+// path is relative to the base URL, e.g. "/shipments", or an absolute URL
+// with the scheme and host of the base URL, such as a pagination link.
+// Other URLs are rejected, so the token is never sent to another host.
+// body, if not nil, is sent as JSON. Send the request with DoRequest.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body interface{}, opts ...RequestOption) (*Request, error) {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		if err := c.checkHost(path); err != nil {
+			return nil, err
+		}
+		return c.newRequestURL(ctx, method, path, body, opts...)
+	}
+	return c.newRequest(ctx, method, path, body, opts...)
+}
+
+// checkHost returns an error unless rawURL has the scheme and host of the
+// base URL
+func (c *Client) checkHost(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse base URL: %w", err)
+	}
+
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return fmt.Errorf("URL %s://%s is not on the API host %s://%s", u.Scheme, u.Host, base.Scheme, base.Host)
+	}
+	return nil
+}
+
+// DoRequest sends req with the client's token refresh, retries, rate
+// limiting, middleware and error decoding. The data field of the response
+// envelope is decoded into out, or the whole body if it is not an
+// envelope; an envelope without data leaves out untouched. out may be nil.
+// The Response carries the pagination of list endpoints.
+func (c *Client) DoRequest(req *Request, out interface{}) (*Response, error) {
+	var v interface{}
+	if out != nil {
+		v = &dataEnvelope{out: out}
+	}
+	return c.do(req, v)
+}
+
+// Do calls an endpoint the SDK does not model, e.g.
+//
+//	var coupons []Coupon
+//	resp, err := client.Do(ctx, "GET", "/coupons", &gosalla.ListOptions{PerPage: 50}, nil, &coupons)
+//
+// query is nil, url.Values or a struct with url tags like ListOptions.
+// body and out are handled as by NewRequest and DoRequest. Errors are
+// returned as for the service methods, e.g. as an *APIError.
+func (c *Client) Do(ctx context.Context, method, path string, query, body, out interface{}, opts ...RequestOption) (*Response, error) {
+	path, err := addQuery(path, query)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.NewRequest(ctx, method, path, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.DoRequest(req, out)
+}
+
+// WithOperation names the operation of a request made with Do or
+// NewRequest, e.g. "coupons.list", for middleware, logs and telemetry
+func WithOperation(name string) RequestOption {
+	return func(r *Request) {
+		r.Operation = name
+	}
+}
+
+// addQuery appends query, which is nil, url.Values or an options struct,
+// to path
+func addQuery(path string, query interface{}) (string, error) {
+	values, ok := query.(url.Values)
+	if !ok {
+		return addOptions(path, query)
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return path, fmt.Errorf("failed to parse path: %w", err)
+	}
+
+	merged := u.Query()
+	for key, vs := range values {
+		for _, v := range vs {
+			merged.Add(key, v)
+		}
+	}
+	u.RawQuery = merged.Encode()
+	return u.String(), nil
+}
+
+// dataEnvelope decodes the data field of a response envelope into out
+type dataEnvelope struct {
+	out interface{}
+}
+
+// UnmarshalJSON decodes the data field, or the whole body if it is not an
+// envelope
+func (e *dataEnvelope) UnmarshalJSON(body []byte) error {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		if data, ok := envelope["data"]; ok {
+			return json.Unmarshal(data, e.out)
+		}
+
+		// An envelope without data, e.g. {"success": true, "code": 200}
+		// after a delete, has nothing to decode
+		_, success := envelope["success"]
+		_, code := envelope["code"]
+		if success || code {
+			return nil
+		}
+	}
+	return json.Unmarshal(body, e.out)
+}
@@ -0,0 +1,124 @@
+package gosalla
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientDo(t *testing.T) {
+	var method, query string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, query = r.Method, r.URL.RawQuery
+		body = nil
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			json.Unmarshal(data, &body)
+		}
+
+		switch r.URL.Path {
+		case "/coupons":
+			w.Write([]byte(`{"success": true, "code": 200, "data": [{"id": 1, "code": "EID"}, {"id": 2, "code": "SALE"}], "pagination": {"current_page": 1, "last_page": 3, "total": 6}}`))
+		case "/coupons/1":
+			w.Write([]byte(`{"success": true, "data": {"id": 1, "code": "EID"}}`))
+		case "/plain":
+			w.Write([]byte(`[1, 2, 3]`))
+		case "/coupons/2":
+			w.Write([]byte(`{"success": true, "code": 200}`))
+		case "/invalid":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"success": false, "error": {"code": "validation", "message": "Invalid", "fields": {"code": ["taken"]}}}`))
+		}
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}), WithBaseURL(server.URL), WithMetrics(recorder))
+	ctx := context.Background()
+
+	type coupon struct {
+		ID   int    `json:"id"`
+		Code string `json:"code"`
+	}
+
+	var coupons []coupon
+	resp, err := client.Do(ctx, "GET", "/coupons", &ListOptions{Page: 1, PerPage: 2}, nil, &coupons, WithOperation("coupons.list"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(coupons) != 2 || coupons[1].Code != "SALE" {
+		t.Errorf("Expected the data to be unwrapped, got %+v", coupons)
+	}
+	if query != "page=1&per_page=2" || resp.Pagination == nil || resp.Pagination.Total != 6 {
+		t.Errorf("Unexpected query %q or pagination %+v", query, resp.Pagination)
+	}
+	if m := recorder.Requests()[0]; m.Operation != "coupons.list" {
+		t.Errorf("Expected the operation to be reported, got %q", m.Operation)
+	}
+
+	var created coupon
+	if _, err := client.Do(ctx, "PUT", "/coupons/1?notify=1", url.Values{"lang": {"en"}}, map[string]string{"code": "EID"}, &created); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if method != "PUT" || query != "lang=en&notify=1" || body["code"] != "EID" || created.ID != 1 {
+		t.Errorf("Unexpected request %s ?%s %v or result %+v", method, query, body, created)
+	}
+
+	var plain []int
+	if _, err := client.Do(ctx, "GET", "/plain", nil, nil, &plain); err != nil || len(plain) != 3 {
+		t.Errorf("Expected a body without envelope to be decoded as is, got %v (%v)", plain, err)
+	}
+
+	deleted := coupon{ID: 2, Code: "SALE"}
+	if _, err := client.Do(ctx, "DELETE", "/coupons/2", nil, nil, &deleted); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deleted.ID != 2 || deleted.Code != "SALE" {
+		t.Errorf("Expected an envelope without data to leave out untouched, got %+v", deleted)
+	}
+
+	_, err = client.Do(ctx, "POST", "/invalid", nil, map[string]string{"code": "EID"}, nil)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields["code"][0] != "taken" {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
+
+func TestClientNewRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"name": "` + r.Header.Get("Authorization") + `"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test", Expiry: time.Now().Add(time.Hour)}), WithBaseURL(server.URL+"/admin/v2"))
+
+	// The token is never sent to another host or over another scheme
+	for _, target := range []string{"https://evil.example.com/reviews", strings.Replace(server.URL, "http://", "https://", 1) + "/reviews"} {
+		if _, err := client.NewRequest(context.Background(), "GET", target, nil); err == nil {
+			t.Errorf("Expected %s to be rejected", target)
+		}
+	}
+
+	// Absolute URLs on the API host bypass the base path
+	req, err := client.NewRequest(context.Background(), "GET", server.URL+"/reviews", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out struct {
+		Name string `json:"name"`
+	}
+	if _, err := client.DoRequest(req, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.Name != "Bearer test" {
+		t.Errorf("Expected an authorized request, got %q", out.Name)
+	}
+}
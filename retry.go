@@ -0,0 +1,145 @@
+package gosalla
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries requests that failed with a
+// retryable error (see IsRetryable): a request timeout (408), a rate limit
+// (429), a server error (5xx) or a TransportError
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the base delay before the first retry. It doubles on
+	// every further retry and is randomized with jitter.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. A Retry-After header
+	// asking for a longer wait ends the retries instead.
+	MaxBackoff time.Duration
+
+	// RetryNonIdempotent allows POST and PATCH requests to be retried.
+	// Only GET, HEAD, OPTIONS, PUT and DELETE are retried by default.
+	RetryNonIdempotent bool
+
+	// OnRetry, if set, is called before the client waits for each retry
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that is about to be retried
+type RetryEvent struct {
+	Request    *http.Request
+	Attempt    int           // the attempt that failed, starting at 1
+	StatusCode int           // 0 if the request never got a response
+	Err        error         // the error returned by the failed attempt
+	Delay      time.Duration // how long the client waits before retrying
+}
+
+// DefaultRetryPolicy returns the retry policy used by new clients
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// retryable reports whether a failed attempt may be retried at all
+func (p *RetryPolicy) retryable(req *http.Request, err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if !p.RetryNonIdempotent && !isIdempotent(req.Method) {
+		return false
+	}
+
+	return IsRetryable(err)
+}
+
+// delay computes the wait before the next attempt. It returns false if the
+// server asked for a longer wait than MaxBackoff allows.
+func (p *RetryPolicy) delay(attempt int, header http.Header) (time.Duration, bool) {
+	if header != nil {
+		if d, ok := parseRetryAfter(header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxBackoff > 0 && d > p.MaxBackoff {
+				return 0, false
+			}
+			return d, true
+		}
+	}
+
+	d := p.MinBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	// Equal jitter: wait at least half the backoff, plus a random share of the rest
+	if half := d / 2; half > 0 {
+		d = half + time.Duration(rand.Int63n(int64(half)))
+	}
+	return d, true
+}
+
+// isIdempotent reports whether a request with the given method can safely be
+// sent more than once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package gosalla
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scope is a permission an app requests from the merchant
+type Scope string
+
+// Salla OAuth scopes. A read_write scope also grants the matching read scope.
+const (
+	ScopeOfflineAccess Scope = "offline_access"
+
+	ScopeProductsRead      Scope = "products.read"
+	ScopeProductsReadWrite Scope = "products.read_write"
+
+	ScopeOrdersRead      Scope = "orders.read"
+	ScopeOrdersReadWrite Scope = "orders.read_write"
+
+	ScopeCustomersRead      Scope = "customers.read"
+	ScopeCustomersReadWrite Scope = "customers.read_write"
+
+	ScopeCategoriesRead      Scope = "categories.read"
+	ScopeCategoriesReadWrite Scope = "categories.read_write"
+
+	ScopeBrandsRead      Scope = "brands.read"
+	ScopeBrandsReadWrite Scope = "brands.read_write"
+)
+
+// ErrInsufficientScope is matched by errors returned when the token was not
+// granted the scope a call requires
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// ScopeError is returned before a request is sent when the token lacks the
+// scope the endpoint requires
+type ScopeError struct {
+	Required Scope
+	Granted  []Scope
+}
+
+// Error implements the error interface
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("insufficient scope: %s is required", e.Required)
+}
+
+// Is lets errors.Is match ErrInsufficientScope
+func (e *ScopeError) Is(target error) bool {
+	return target == ErrInsufficientScope
+}
+
+// parseScopes splits a space separated scope string as returned by the
+// token endpoint
+func parseScopes(s string) []Scope {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	scopes := make([]Scope, len(fields))
+	for i, field := range fields {
+		scopes[i] = Scope(field)
+	}
+	return scopes
+}
+
+// joinScopes joins scopes into a space separated string
+func joinScopes(scopes []Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, " ")
+}
+
+// HasScope reports whether the token was granted scope. A token whose
+// scopes are unknown, e.g. one loaded from storage that did not keep them,
+// is assumed to have every scope.
+func (t *Token) HasScope(scope Scope) bool {
+	if len(t.Scopes) == 0 {
+		return true
+	}
+
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+
+		// products.read_write also grants products.read
+		if read, ok := strings.CutSuffix(string(granted), ".read_write"); ok && Scope(read+".read") == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScope returns a ScopeError if the token lacks scope
+func (t *Token) checkScope(scope Scope) error {
+	if scope == "" || t == nil || t.HasScope(scope) {
+		return nil
+	}
+	return &ScopeError{Required: scope, Granted: t.Scopes}
+}
@@ -0,0 +1,118 @@
+package gosalla
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultStateTTL is how long an OAuth state issued by a StateSigner stays valid
+const DefaultStateTTL = 10 * time.Minute
+
+// minStateSecret is the shortest secret NewStateSigner accepts
+const minStateSecret = 32
+
+var (
+	// ErrInvalidState is returned when an OAuth state was not issued by the
+	// StateSigner or has been tampered with
+	ErrInvalidState = errors.New("invalid oauth state")
+
+	// ErrStateExpired is returned when an OAuth state is older than its TTL
+	ErrStateExpired = errors.New("oauth state expired")
+)
+
+// StateSigner issues and verifies OAuth state values. A state is an
+// HMAC-signed, expiring token that can carry a payload such as the URL to
+// return the merchant to after authorization, so no server-side storage is
+// needed. A state proves that the app issued it, not to whom: tie it to the
+// browser that started the flow, as OAuthCallbackHandler does with a
+// cookie, to protect against forged callbacks.
+type StateSigner struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewStateSigner creates a StateSigner that signs states with secret. States
+// expire after ttl, or DefaultStateTTL if ttl is zero. The secret must be at
+// least 32 random bytes and shared by every instance of the app.
+func NewStateSigner(secret []byte, ttl time.Duration) (*StateSigner, error) {
+	if len(secret) < minStateSecret {
+		return nil, fmt.Errorf("state secret must be at least %d bytes, got %d", minStateSecret, len(secret))
+	}
+	if ttl <= 0 {
+		ttl = DefaultStateTTL
+	}
+	return &StateSigner{secret: secret, ttl: ttl, now: time.Now}, nil
+}
+
+// statePayload is the signed content of a state
+type statePayload struct {
+	Nonce   string `json:"n"`
+	Expires int64  `json:"e"`
+	Data    string `json:"d,omitempty"`
+}
+
+// New issues a state carrying payload, which may be empty
+func (s *StateSigner) New(payload string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate state nonce: %w", err)
+	}
+
+	body, err := json.Marshal(statePayload{
+		Nonce:   base64.RawURLEncoding.EncodeToString(nonce),
+		Expires: s.now().Add(s.ttl).Unix(),
+		Data:    payload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(body)
+	return encoded + "." + s.sign(encoded), nil
+}
+
+// Verify checks that state was issued by s and has not expired, and returns
+// the payload it carries
+func (s *StateSigner) Verify(state string) (string, error) {
+	encoded, signature, ok := strings.Cut(state, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return "", ErrInvalidState
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidState
+	}
+
+	var payload statePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", ErrInvalidState
+	}
+
+	if s.now().Unix() > payload.Expires {
+		return "", ErrStateExpired
+	}
+
+	return payload.Data, nil
+}
+
+// verifier derives a PKCE code verifier from state, so the verifier does not
+// have to be stored between the redirect and the callback
+func (s *StateSigner) verifier(state string) string {
+	return s.sign("pkce." + state)
+}
+
+// sign returns the encoded HMAC-SHA256 signature of value
+func (s *StateSigner) sign(value string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStateSecret is a state secret of the minimum length
+var testStateSecret = []byte(strings.Repeat("s", minStateSecret))
+
+func TestStateSigner(t *testing.T) {
+	signer, err := NewStateSigner(testStateSecret, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	state, err := signer.New("/dashboard")
+	if err != nil {
+		t.Fatalf("Failed to issue state: %v", err)
+	}
+
+	payload, err := signer.Verify(state)
+	if err != nil || payload != "/dashboard" {
+		t.Errorf("Expected payload /dashboard, got %q (%v)", payload, err)
+	}
+
+	another, _ := signer.New("/dashboard")
+	if another == state {
+		t.Error("Expected every state to be unique")
+	}
+
+	other, _ := NewStateSigner([]byte(strings.Repeat("o", minStateSecret)), time.Minute)
+	if _, err := other.Verify(state); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for another secret, got %v", err)
+	}
+
+	if _, err := signer.Verify(strings.Replace(state, ".", "x.", 1)); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for a tampered state, got %v", err)
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := signer.Verify(state); !errors.Is(err, ErrStateExpired) {
+		t.Errorf("Expected ErrStateExpired, got %v", err)
+	}
+
+	for _, secret := range []string{"", "secret"} {
+		if _, err := NewStateSigner([]byte(secret), 0); err == nil {
+			t.Errorf("Expected an error for the secret %q", secret)
+		}
+	}
+}
+
+func TestOAuthCallbackHandler(t *testing.T) {
+	var form url.Values
+	tokenServer := newTokenServer(t, &form)
+
+	config := &OAuthConfig{ClientID: "client", TokenURL: tokenServer.URL}
+
+	states, _ := NewStateSigner(testStateSecret, 0)
+
+	var saved *Token
+	handler := NewOAuthCallbackHandler(config, states, func(ctx context.Context, token *Token) error {
+		saved = token
+		return nil
+	})
+	handler.PKCE = true
+
+	issued := httptest.NewRecorder()
+	authURL, err := handler.AuthorizationURL(issued, "/settings")
+	if err != nil {
+		t.Fatalf("Failed to build authorization URL: %v", err)
+	}
+
+	parsed, _ := url.Parse(authURL)
+	state := parsed.Query().Get("state")
+	challenge := parsed.Query().Get("code_challenge")
+
+	// A valid state from another browser is rejected
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?code=code-1&state="+url.QueryEscape(state), nil))
+	if rec.Code != http.StatusBadRequest || saved != nil {
+		t.Errorf("Expected 400 for a state without its cookie, got %d", rec.Code)
+	}
+
+	callback := httptest.NewRequest("GET", "/callback?code=code-1&state="+url.QueryEscape(state), nil)
+	for _, cookie := range issued.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, callback)
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/settings" {
+		t.Errorf("Expected redirect to /settings, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if saved == nil || saved.AccessToken != "access-authorization_code" || form.Get("code") != "code-1" {
+		t.Errorf("Expected exchanged token to be saved, got %+v", saved)
+	}
+
+	if !VerifyS256Challenge(form.Get("code_verifier"), challenge) {
+		t.Errorf("Expected verifier matching challenge %q, got %q", challenge, form.Get("code_verifier"))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/callback?code=code-1&state=forged", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a forged state, got %d", rec.Code)
+	}
+
+	var handlerErr error
+	handler.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handlerErr = err
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/callback?error=access_denied&state="+url.QueryEscape(state), nil))
+	if !IsAccessDenied(handlerErr) {
+		t.Errorf("Expected access denied error, got %v", handlerErr)
+	}
+
+	if _, err := handler.AuthorizationURL(httptest.NewRecorder(), "https://evil.example.com"); err == nil {
+		t.Error("Expected an error for a non-local return path")
+	}
+}
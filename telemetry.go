@@ -0,0 +1,235 @@
+package gosalla
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans around API requests, token refreshes and webhook
+// dispatch. Implement it to bridge gosalla to a tracing system such as
+// OpenTelemetry.
+type Tracer interface {
+	// Start starts a span and returns a context carrying it. The context
+	// is used for the traced work, so spans started by the HTTP client or
+	// webhook handlers become children of it.
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+// Span is a traced unit of work started by a Tracer
+type Span interface {
+	// SetAttributes adds attributes to the span
+	SetAttributes(attrs ...slog.Attr)
+
+	// RecordError marks the span as failed with err
+	RecordError(err error)
+
+	// End finishes the span
+	End()
+}
+
+// Metrics receives a measurement for every API request, token refresh and
+// webhook dispatch. Implement it to export counters and latency histograms,
+// e.g. to Prometheus. Methods may be called concurrently.
+type Metrics interface {
+	ObserveRequest(RequestMetric)
+	ObserveTokenRefresh(TokenRefreshMetric)
+	ObserveWebhook(WebhookMetric)
+}
+
+// RequestMetric describes a finished API call, including all its attempts
+type RequestMetric struct {
+	Operation  string        // e.g. "products.get"
+	Method     string        // HTTP method
+	Merchant   int           // merchant set with WithMerchant, or 0
+	StatusCode int           // status of the last attempt, or 0 without a response
+	Retries    int           // attempts after the first
+	Duration   time.Duration // time for the whole call
+	Err        error         // the error returned to the caller, if any
+}
+
+// TokenRefreshMetric describes a finished token refresh
+type TokenRefreshMetric struct {
+	Merchant int // merchant set with WithMerchant, or 0
+
+	// Refreshed reports whether the token endpoint issued a new token, as
+	// opposed to a newer token being loaded from a store
+	Refreshed bool
+
+	Duration time.Duration
+	Err      error
+}
+
+// WebhookMetric describes a dispatched webhook delivery
+type WebhookMetric struct {
+	Event    string // event type, empty if the payload could not be parsed
+	Merchant int
+	Duration time.Duration
+	Err      error // the error returned by the handler, if any
+}
+
+// WithTracer sets the tracer the client starts spans with
+func WithTracer(tracer Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+// WithMetrics sets the metrics the client reports to
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Client) {
+		c.metrics = metrics
+	}
+}
+
+// WithMerchant sets the merchant the client acts for, which is reported to
+// the tracer and metrics. ClientPool sets it for every client.
+func WithMerchant(merchantID int) Option {
+	return func(c *Client) {
+		c.merchantID = merchantID
+	}
+}
+
+// startSpan starts a span if tracer is set. The returned span is never nil.
+func startSpan(ctx context.Context, tracer Tracer, name string, attrs ...slog.Attr) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name, attrs...)
+}
+
+// noopSpan is used when no tracer is set
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// Recorder is an in-memory Tracer and Metrics, for tests. It is safe for
+// concurrent use.
+type Recorder struct {
+	mu             sync.Mutex
+	spans          []*RecordedSpan
+	requests       []RequestMetric
+	tokenRefreshes []TokenRefreshMetric
+	webhooks       []WebhookMetric
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// RecordedSpan is a span captured by a Recorder
+type RecordedSpan struct {
+	Name  string
+	Attrs []slog.Attr
+	Err   error
+	Start time.Time
+	End   time.Time // zero until the span has ended
+
+	recorder *Recorder
+}
+
+// Attr returns the value of the attribute key and whether it was set
+func (s *RecordedSpan) Attr(key string) (slog.Value, bool) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	for i := len(s.Attrs) - 1; i >= 0; i-- {
+		if s.Attrs[i].Key == key {
+			return s.Attrs[i].Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// Start implements Tracer
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	span := &RecordedSpan{Name: name, Attrs: attrs, Start: time.Now(), recorder: r}
+	r.spans = append(r.spans, span)
+	return ctx, recordingSpan{span}
+}
+
+// recordingSpan updates a RecordedSpan under the recorder's lock
+type recordingSpan struct {
+	span *RecordedSpan
+}
+
+func (s recordingSpan) SetAttributes(attrs ...slog.Attr) {
+	s.span.recorder.mu.Lock()
+	defer s.span.recorder.mu.Unlock()
+	s.span.Attrs = append(s.span.Attrs, attrs...)
+}
+
+func (s recordingSpan) RecordError(err error) {
+	s.span.recorder.mu.Lock()
+	defer s.span.recorder.mu.Unlock()
+	s.span.Err = err
+}
+
+func (s recordingSpan) End() {
+	s.span.recorder.mu.Lock()
+	defer s.span.recorder.mu.Unlock()
+	s.span.End = time.Now()
+}
+
+// ObserveRequest implements Metrics
+func (r *Recorder) ObserveRequest(m RequestMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, m)
+}
+
+// ObserveTokenRefresh implements Metrics
+func (r *Recorder) ObserveTokenRefresh(m TokenRefreshMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokenRefreshes = append(r.tokenRefreshes, m)
+}
+
+// ObserveWebhook implements Metrics
+func (r *Recorder) ObserveWebhook(m WebhookMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webhooks = append(r.webhooks, m)
+}
+
+// Spans returns the spans started so far, in order
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// Requests returns the API calls observed so far, in order
+func (r *Recorder) Requests() []RequestMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RequestMetric(nil), r.requests...)
+}
+
+// TokenRefreshes returns the token refreshes observed so far, in order
+func (r *Recorder) TokenRefreshes() []TokenRefreshMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TokenRefreshMetric(nil), r.tokenRefreshes...)
+}
+
+// Webhooks returns the webhook deliveries observed so far, in order
+func (r *Recorder) Webhooks() []WebhookMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]WebhookMetric(nil), r.webhooks...)
+}
+
+// Reset discards everything recorded so far
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans, r.requests, r.tokenRefreshes, r.webhooks = nil, nil, nil, nil
+}
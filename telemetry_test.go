@@ -0,0 +1,125 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientTelemetry(t *testing.T) {
+	var form url.Values
+	tokenServer := newTokenServer(t, &form)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": {"id": 5}}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := NewClient(
+		WithToken(&OAuthConfig{TokenURL: tokenServer.URL}, &Token{
+			AccessToken:  "expired",
+			RefreshToken: "refresh-1",
+			Expiry:       time.Now().Add(-time.Minute),
+		}),
+		WithBaseURL(server.URL),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+		WithMerchant(42),
+		WithTracer(recorder),
+		WithMetrics(recorder),
+	)
+
+	if _, _, err := client.Categories.Get(context.Background(), 5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	requests := recorder.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request metric, got %+v", requests)
+	}
+	if m := requests[0]; m.Operation != "categories.get" || m.Method != "GET" || m.Merchant != 42 || m.StatusCode != 200 || m.Retries != 1 || m.Duration <= 0 || m.Err != nil {
+		t.Errorf("Unexpected request metric: %+v", m)
+	}
+
+	refreshes := recorder.TokenRefreshes()
+	if len(refreshes) != 1 || !refreshes[0].Refreshed || refreshes[0].Merchant != 42 || refreshes[0].Err != nil {
+		t.Errorf("Unexpected token refresh metrics: %+v", refreshes)
+	}
+
+	spans := recorder.Spans()
+	if len(spans) != 2 || spans[0].Name != "salla categories.get" || spans[1].Name != "salla token.refresh" {
+		t.Fatalf("Expected request and refresh spans, got %+v", spans)
+	}
+	if status, _ := spans[0].Attr("http.status_code"); status.Int64() != 200 {
+		t.Errorf("Expected status attribute 200, got %v", status)
+	}
+	if retries, _ := spans[0].Attr("salla.retries"); retries.Int64() != 1 {
+		t.Errorf("Expected retries attribute 1, got %v", retries)
+	}
+	for _, span := range spans {
+		if span.End.IsZero() {
+			t.Errorf("Expected span %s to be ended", span.Name)
+		}
+	}
+}
+
+func TestClientTelemetryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := NewClient(WithToken(&OAuthConfig{}, &Token{AccessToken: "test"}), WithBaseURL(server.URL), WithTracer(recorder), WithMetrics(recorder))
+
+	_, err := client.Products.Delete(context.Background(), 9)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+
+	if m := recorder.Requests()[0]; m.StatusCode != 404 || m.Retries != 0 || !errors.Is(m.Err, ErrNotFound) {
+		t.Errorf("Unexpected request metric: %+v", m)
+	}
+	if span := recorder.Spans()[0]; !errors.Is(span.Err, ErrNotFound) {
+		t.Errorf("Expected the span to record the error, got %v", span.Err)
+	}
+}
+
+func TestWebhookTelemetry(t *testing.T) {
+	recorder := NewRecorder()
+	handler := NewWebhookHandler("")
+	handler.Tracer = recorder
+	handler.Metrics = recorder
+
+	handler.On(EventOrderCreated, func(event *WebhookEvent) error {
+		if event.Context() == nil {
+			t.Error("Expected the event to carry a context")
+		}
+		return errors.New("boom")
+	})
+
+	payload := `{"event": "order.created", "merchant": 7, "data": {"id": 1}}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook", strings.NewReader(payload)))
+
+	webhooks := recorder.Webhooks()
+	if len(webhooks) != 1 || webhooks[0].Event != EventOrderCreated || webhooks[0].Merchant != 7 || webhooks[0].Err == nil {
+		t.Errorf("Unexpected webhook metrics: %+v", webhooks)
+	}
+
+	spans := recorder.Spans()
+	if len(spans) != 1 || spans[0].Name != "salla webhook order.created" || spans[0].Err == nil {
+		t.Errorf("Unexpected webhook spans: %+v", spans)
+	}
+}
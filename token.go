@@ -0,0 +1,305 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// expiryDelta is how long before its expiry a token is refreshed
+const expiryDelta = 5 * time.Minute
+
+// errNoRefreshToken is returned when a token needs refreshing but has no refresh token
+var errNoRefreshToken = fmt.Errorf("no refresh token available: %w", ErrReauthorizationRequired)
+
+// TokenSource supplies the tokens used to authorize API requests
+type TokenSource interface {
+	// Token returns a token for the next request, refreshing it if needed
+	Token(ctx context.Context) (*Token, error)
+}
+
+// StaticTokenSource returns a TokenSource that always returns the same
+// token and never refreshes it
+func StaticTokenSource(token *Token) TokenSource {
+	return staticTokenSource{token: token}
+}
+
+type staticTokenSource struct {
+	token *Token
+}
+
+func (s staticTokenSource) Token(ctx context.Context) (*Token, error) {
+	return s.token, nil
+}
+
+// TokenSource returns a TokenSource that starts with token and refreshes it
+// through c when it is about to expire
+func (c *OAuthConfig) TokenSource(token *Token) TokenSource {
+	return newRefreshingTokenSource(c, token)
+}
+
+// StoreTokenSource returns a TokenSource for the given merchant that loads
+// its token from store, refreshes it through c and saves every refreshed
+// token back to store
+func (c *OAuthConfig) StoreTokenSource(store TokenStore, merchantID int) TokenSource {
+	s := newRefreshingTokenSource(c, nil)
+	s.load = func(ctx context.Context) (*Token, error) {
+		return store.Get(ctx, merchantID)
+	}
+	s.save = func(ctx context.Context, token *Token) error {
+		return store.Save(ctx, merchantID, token)
+	}
+	return s
+}
+
+// needsRefresh reports whether t should be refreshed before it is used.
+// Tokens without an expiry are used until the API rejects them.
+func (t *Token) needsRefresh(now time.Time) bool {
+	if t == nil || t.AccessToken == "" {
+		return true
+	}
+	return !t.Expiry.IsZero() && now.Add(expiryDelta).After(t.Expiry)
+}
+
+// refreshingTokenSource holds a token and replaces it when it is about to
+// expire or has been rejected. Concurrent callers share a single refresh.
+type refreshingTokenSource struct {
+	// load, if set, fetches the latest token from outside the source, such
+	// as a token store or another TokenSource
+	load func(ctx context.Context) (*Token, error)
+
+	// refreshFn exchanges a refresh token for a new token
+	refreshFn func(ctx context.Context, refreshToken string) (*Token, error)
+
+	// save, if set, persists every refreshed token
+	save func(ctx context.Context, token *Token) error
+
+	mu        sync.Mutex
+	token     *Token
+	flight    *refreshCall
+	onRefresh func(*Token)
+	onReauth  func(error)
+
+	// reauthErr, once the token can no longer be refreshed, is returned
+	// to every caller until set replaces the token
+	reauthErr error
+
+	// observe, if set, is called when a refresh starts and returns the
+	// function that reports its outcome
+	observe func(ctx context.Context) (context.Context, func(refreshed bool, err error))
+}
+
+// newRefreshingTokenSource creates a source holding token that refreshes it
+// through config
+func newRefreshingTokenSource(config *OAuthConfig, token *Token) *refreshingTokenSource {
+	s := &refreshingTokenSource{token: token}
+	if config != nil {
+		s.refreshFn = config.RefreshToken
+	}
+	return s
+}
+
+// wrapTokenSource adapts any TokenSource so the client can cache its token
+// and share refreshes between concurrent requests
+func wrapTokenSource(src TokenSource) *refreshingTokenSource {
+	if s, ok := src.(*refreshingTokenSource); ok {
+		return s
+	}
+	return &refreshingTokenSource{load: src.Token}
+}
+
+// refreshCall is a refresh in progress that callers can wait on
+type refreshCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// current returns the held token without refreshing it
+func (s *refreshingTokenSource) current() *Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// set replaces the held token
+func (s *refreshingTokenSource) set(token *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	s.reauthErr = nil
+}
+
+// setObserver sets the function that instruments refreshes
+func (s *refreshingTokenSource) setObserver(fn func(ctx context.Context) (context.Context, func(refreshed bool, err error))) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observe = fn
+}
+
+// setOnRefresh sets the callback invoked with every refreshed token
+func (s *refreshingTokenSource) setOnRefresh(fn func(*Token)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRefresh = fn
+}
+
+// setOnReauthorizationRequired sets the callback invoked when the token
+// can no longer be refreshed
+func (s *refreshingTokenSource) setOnReauthorizationRequired(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReauth = fn
+}
+
+// Token returns the held token, refreshing it first if it is about to expire
+func (s *refreshingTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	token, reauthErr := s.token, s.reauthErr
+	s.mu.Unlock()
+
+	if reauthErr != nil {
+		return nil, reauthErr
+	}
+	if !token.needsRefresh(time.Now()) {
+		return token, nil
+	}
+	return s.refresh(ctx, token)
+}
+
+// refresh replaces stale with a freshly issued token. If another caller has
+// already replaced stale, the newer token is returned without a new request.
+func (s *refreshingTokenSource) refresh(ctx context.Context, stale *Token) (*Token, error) {
+	s.mu.Lock()
+	if s.reauthErr != nil {
+		err := s.reauthErr
+		s.mu.Unlock()
+		return nil, err
+	}
+	if s.token != stale && !s.token.needsRefresh(time.Now()) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	call := s.flight
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		s.flight = call
+
+		// The refresh is shared, so it must outlive the caller that started it
+		go s.run(context.WithoutCancel(ctx), call, s.token)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run obtains a replacement for old and publishes the result to call
+func (s *refreshingTokenSource) run(ctx context.Context, call *refreshCall, old *Token) {
+	s.mu.Lock()
+	observe := s.observe
+	s.mu.Unlock()
+
+	var done func(refreshed bool, err error)
+	if observe != nil {
+		ctx, done = observe(ctx)
+	}
+
+	token, refreshed, err := s.obtain(ctx, old)
+	if done != nil {
+		done(refreshed, err)
+	}
+
+	s.mu.Lock()
+	onRefresh, onReauth := s.onRefresh, s.onReauth
+	s.mu.Unlock()
+
+	// Let the callback persist the token before any caller can use it.
+	// Callers that need a new token wait on the refresh in flight.
+	if refreshed && onRefresh != nil {
+		onRefresh(token)
+	}
+
+	s.mu.Lock()
+	s.flight = nil
+	if token != nil && s.token == old {
+		s.token = token
+	}
+
+	// A grant that cannot be refreshed stays unusable, so later callers
+	// fail without asking the token endpoint again
+	if errors.Is(err, ErrReauthorizationRequired) && s.token == old {
+		s.reauthErr = err
+	}
+	s.mu.Unlock()
+
+	if onReauth != nil && errors.Is(err, ErrReauthorizationRequired) {
+		onReauth(err)
+	}
+
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+// obtain loads or refreshes a token to replace old. refreshed reports
+// whether the token endpoint issued a new token; if saving that token
+// fails, it is returned together with the error so it is not lost.
+func (s *refreshingTokenSource) obtain(ctx context.Context, old *Token) (token *Token, refreshed bool, err error) {
+	base := old
+	if s.load != nil {
+		loaded, err := s.load(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to load token: %w", err)
+		}
+
+		// The loaded token may already have been refreshed elsewhere
+		if !loaded.needsRefresh(time.Now()) && (old == nil || loaded.AccessToken != old.AccessToken) {
+			return loaded, false, nil
+		}
+		base = loaded
+	}
+
+	if s.refreshFn == nil {
+		// A wrapped source refreshes its own tokens, so its token is used
+		// until it expires rather than until the refresh margin. A token
+		// rejected by the API is not handed out again.
+		rejected := old != nil && !old.needsRefresh(time.Now())
+		if base != nil && base.Valid() && (!rejected || base.AccessToken != old.AccessToken) {
+			return base, false, nil
+		}
+		return nil, false, fmt.Errorf("token source cannot refresh the token")
+	}
+	if base == nil || base.RefreshToken == "" {
+		return nil, false, errNoRefreshToken
+	}
+
+	token, err = s.refreshFn(ctx, base.RefreshToken)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	// Keep the previous refresh token and scopes if the server did not
+	// report new ones
+	if token.RefreshToken == "" {
+		token.RefreshToken = base.RefreshToken
+	}
+	if len(token.Scopes) == 0 {
+		token.Scopes = base.Scopes
+	}
+
+	if s.save != nil {
+		if err := s.save(ctx, token); err != nil {
+			return token, true, fmt.Errorf("failed to save refreshed token: %w", err)
+		}
+	}
+
+	return token, true, nil
+}
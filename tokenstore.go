@@ -0,0 +1,209 @@
+package gosalla
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrTokenNotFound is returned by a TokenStore when it holds no token for a merchant
+var ErrTokenNotFound = errors.New("token not found")
+
+// TokenStore persists OAuth tokens keyed by Salla merchant ID.
+// Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Get returns the token stored for merchantID, or ErrTokenNotFound
+	Get(ctx context.Context, merchantID int) (*Token, error)
+
+	// Save stores token for merchantID, replacing any previous token
+	Save(ctx context.Context, merchantID int, token *Token) error
+
+	// Delete removes the token stored for merchantID, if any
+	Delete(ctx context.Context, merchantID int) error
+}
+
+// MemoryTokenStore is a TokenStore that keeps tokens in memory
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[int]Token
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[int]Token)}
+}
+
+// Get returns the token stored for merchantID
+func (s *MemoryTokenStore) Get(ctx context.Context, merchantID int) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[merchantID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+// Save stores a copy of token for merchantID
+func (s *MemoryTokenStore) Save(ctx context.Context, merchantID int, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[merchantID] = *token
+	return nil
+}
+
+// Delete removes the token stored for merchantID
+func (s *MemoryTokenStore) Delete(ctx context.Context, merchantID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, merchantID)
+	return nil
+}
+
+// FileTokenStore is a TokenStore that keeps all tokens in a single JSON
+// file. Every write replaces the file atomically, so a crash never leaves a
+// partially written file behind.
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// storedToken is the on-disk representation of a Token
+type storedToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+	Scope        string    `json:"scope,omitempty"`
+}
+
+// NewFileTokenStore creates a token store backed by the file at path.
+// The file is created on the first Save.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Get returns the token stored for merchantID
+func (s *FileTokenStore) Get(ctx context.Context, merchantID int) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, ok := tokens[strconv.Itoa(merchantID)]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return &Token{
+		AccessToken:  stored.AccessToken,
+		RefreshToken: stored.RefreshToken,
+		TokenType:    stored.TokenType,
+		Expiry:       stored.Expiry,
+		Scopes:       parseScopes(stored.Scope),
+	}, nil
+}
+
+// Save stores token for merchantID
+func (s *FileTokenStore) Save(ctx context.Context, merchantID int, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	tokens[strconv.Itoa(merchantID)] = storedToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		Scope:        joinScopes(token.Scopes),
+	}
+
+	return s.write(tokens)
+}
+
+// Delete removes the token stored for merchantID
+func (s *FileTokenStore) Delete(ctx context.Context, merchantID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	key := strconv.Itoa(merchantID)
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+
+	return s.write(tokens)
+}
+
+// read loads all tokens from the file; a missing file holds no tokens
+func (s *FileTokenStore) read() (map[string]storedToken, error) {
+	tokens := make(map[string]storedToken)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to parse token file: %w", err)
+		}
+	}
+
+	return tokens, nil
+}
+
+// write replaces the file with tokens by writing a temporary file in the
+// same directory and renaming it over the original
+func (s *FileTokenStore) write(tokens map[string]storedToken) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace token file: %w", err)
+	}
+
+	return nil
+}
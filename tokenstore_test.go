@@ -0,0 +1,80 @@
+package gosalla
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testTokenStore(t *testing.T, store TokenStore) {
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, 1); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("Expected ErrTokenNotFound, got %v", err)
+	}
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := &Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: expiry}
+	if err := store.Save(ctx, 1, token); err != nil {
+		t.Fatalf("Failed to save token: %v", err)
+	}
+
+	got, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Failed to get token: %v", err)
+	}
+
+	if got.AccessToken != "access" || got.RefreshToken != "refresh" || !got.Expiry.Equal(expiry) {
+		t.Errorf("Unexpected token: %+v", got)
+	}
+
+	if err := store.Delete(ctx, 1); err != nil {
+		t.Fatalf("Failed to delete token: %v", err)
+	}
+
+	if _, err := store.Get(ctx, 1); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Expected ErrTokenNotFound after delete, got %v", err)
+	}
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	testTokenStore(t, NewMemoryTokenStore())
+}
+
+func TestFileTokenStore(t *testing.T) {
+	testTokenStore(t, NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json")))
+}
+
+func TestStoreTokenSourceSavesRefreshedToken(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+	store.Save(ctx, 42, &Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(-time.Minute),
+	})
+
+	source := (&OAuthConfig{}).StoreTokenSource(store, 42).(*refreshingTokenSource)
+	source.refreshFn = func(ctx context.Context, refreshToken string) (*Token, error) {
+		if refreshToken != "refresh" {
+			t.Errorf("Expected stored refresh token, got %q", refreshToken)
+		}
+		return &Token{AccessToken: "fresh", RefreshToken: "rotated", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+
+	token, err := source.Token(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if token.AccessToken != "fresh" {
+		t.Errorf("Expected refreshed token, got %q", token.AccessToken)
+	}
+
+	stored, _ := store.Get(ctx, 42)
+	if stored.RefreshToken != "rotated" {
+		t.Errorf("Expected rotated refresh token in store, got %q", stored.RefreshToken)
+	}
+}
@@ -0,0 +1,134 @@
+package gosalla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultUserInfoURL is Salla's OAuth user info endpoint
+const DefaultUserInfoURL = "https://accounts.salla.sa/oauth2/user/info"
+
+// UserInfo describes the merchant user a token was issued for and the
+// store it belongs to
+type UserInfo struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
+	Role   string `json:"role"`
+
+	// Merchant is the store the app is installed on. Its ID is the key to
+	// store the merchant's token under.
+	Merchant Merchant `json:"merchant"`
+
+	// Context describes the token itself
+	Context TokenContext `json:"context"`
+}
+
+// Merchant represents a Salla store
+type Merchant struct {
+	ID            int    `json:"id"`
+	Username      string `json:"username"`
+	Name          string `json:"name"`
+	Avatar        string `json:"avatar,omitempty"`
+	StoreLocation string `json:"store_location,omitempty"`
+	Plan          string `json:"plan"`
+	Status        string `json:"status"`
+	Domain        string `json:"domain"`
+}
+
+// TokenContext describes the app and grant a token belongs to
+type TokenContext struct {
+	App       int
+	Scopes    []Scope
+	ExpiresAt time.Time
+}
+
+// UnmarshalJSON decodes the token context from its wire format
+func (c *TokenContext) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		App   int    `json:"app"`
+		Scope string `json:"scope"`
+		Exp   int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.App = raw.App
+	c.Scopes = parseScopes(raw.Scope)
+	if raw.Exp > 0 {
+		c.ExpiresAt = time.Unix(raw.Exp, 0)
+	}
+	return nil
+}
+
+// UserInfoResponse represents the response from the user info endpoint
+type UserInfoResponse struct {
+	Success bool     `json:"success"`
+	Status  int      `json:"status"`
+	Data    UserInfo `json:"data"`
+}
+
+// UserInfo returns the merchant user and store that token was issued for,
+// e.g. to learn which merchant to store a freshly exchanged token under
+func (c *OAuthConfig) UserInfo(ctx context.Context, token *Token) (*UserInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.userInfoURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+
+	resp, err := c.send(&Request{Request: req, Operation: "oauth.user_info"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request user info: %w", &TransportError{Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result UserInfoResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse user info: %w", err)
+	}
+
+	return &result.Data, nil
+}
+
+// userInfoURL returns the user info endpoint
+func (c *OAuthConfig) userInfoURL() string {
+	if c != nil && c.UserInfoURL != "" {
+		return c.UserInfoURL
+	}
+	return DefaultUserInfoURL
+}
+
+// Me returns the merchant user and store the client's token belongs to
+func (c *Client) Me(ctx context.Context, reqOpts ...RequestOption) (*UserInfo, *Response, error) {
+	req, err := c.newRequestURL(ctx, "GET", c.oauthConfig.userInfoURL(), nil, reqOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Operation = "oauth.user_info"
+
+	var result UserInfoResponse
+	resp, err := c.do(req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &result.Data, resp, nil
+}
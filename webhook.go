@@ -1,13 +1,16 @@
 package gosalla
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -51,6 +54,20 @@ const (
 	// Shipping events
 	EventShipmentCreated = "shipment.created"
 	EventShipmentUpdated = "shipment.updated"
+	
+	// App lifecycle events
+	EventAppStoreAuthorize       = "app.store.authorize"
+	EventAppInstalled            = "app.installed"
+	EventAppUninstalled          = "app.uninstalled"
+	EventAppUpdated              = "app.updated"
+	EventAppSettingsUpdated      = "app.settings.updated"
+	EventAppTrialStarted         = "app.trial.started"
+	EventAppTrialExpired         = "app.trial.expired"
+	EventAppTrialCanceled        = "app.trial.canceled"
+	EventAppSubscriptionStarted  = "app.subscription.started"
+	EventAppSubscriptionExpired  = "app.subscription.expired"
+	EventAppSubscriptionCanceled = "app.subscription.canceled"
+	EventAppSubscriptionRenewed  = "app.subscription.renewed"
 )
 
 // WebhookEvent represents a webhook event from Salla
@@ -59,6 +76,49 @@ type WebhookEvent struct {
 	Merchant  int                    `json:"merchant"`
 	Data      map[string]interface{} `json:"data"`
 	CreatedAt time.Time              `json:"created_at"`
+	
+	ctx context.Context
+}
+
+// Context returns the context of the delivery the event arrived with,
+// carrying the webhook span if a Tracer is set. It is never nil.
+func (e *WebhookEvent) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// webhookTimeLayout is the layout Salla uses for created_at in webhook payloads
+const webhookTimeLayout = "2006-01-02 15:04:05"
+
+// UnmarshalJSON decodes a webhook event, accepting created_at both in
+// RFC 3339 and in Salla's "2006-01-02 15:04:05" layout
+func (e *WebhookEvent) UnmarshalJSON(data []byte) error {
+	type event WebhookEvent
+	aux := struct {
+		*event
+		CreatedAt string `json:"created_at"`
+	}{event: (*event)(e)}
+	
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	
+	e.CreatedAt = time.Time{}
+	if aux.CreatedAt == "" {
+		return nil
+	}
+	
+	createdAt, err := time.Parse(time.RFC3339, aux.CreatedAt)
+	if err != nil {
+		createdAt, err = time.Parse(webhookTimeLayout, aux.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("invalid created_at %q: %w", aux.CreatedAt, err)
+		}
+	}
+	e.CreatedAt = createdAt
+	return nil
 }
 
 // ProductWebhookEvent represents a product-related webhook event
@@ -86,7 +146,7 @@ type CustomerWebhookEvent struct {
 }
 
 // VerifyWebhookSignature verifies the HMAC signature of a webhook request
-// The signature is typically sent in the X-Signature header
+// Salla sends the signature in the X-Salla-Signature header
 func VerifyWebhookSignature(secret string, payload []byte, signature string) bool {
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write(payload)
@@ -138,6 +198,14 @@ type WebhookHandler func(*WebhookEvent) error
 type WebhookHandlerFunc struct {
 	Secret   string
 	Handlers map[string]WebhookHandler
+	
+	// Logger, if set, receives a record of every delivery, with signatures
+	// and customer contact details redacted
+	Logger *slog.Logger
+	
+	// Tracer and Metrics, if set, are reported every dispatched event
+	Tracer  Tracer
+	Metrics Metrics
 }
 
 // NewWebhookHandler creates a new webhook handler
@@ -193,6 +261,8 @@ func (h *WebhookHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	start := time.Now()
+	
 	// Read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -203,13 +273,17 @@ func (h *WebhookHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	
 	// Verify signature if secret is provided
 	if h.Secret != "" {
-		signature := r.Header.Get("X-Signature")
+		signature := r.Header.Get("X-Salla-Signature")
+		if signature == "" {
+			signature = r.Header.Get("X-Signature")
+		}
 		if signature == "" {
 			// Also check for Authorization header
 			signature = r.Header.Get("Authorization")
 		}
 		
 		if !VerifyWebhookSignature(h.Secret, body, signature) {
+			h.log(r.Context(), slog.LevelWarn, "invalid webhook signature", nil, start)
 			http.Error(w, "Invalid signature", http.StatusUnauthorized)
 			return
 		}
@@ -218,27 +292,86 @@ func (h *WebhookHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Parse the webhook event
 	event, err := ParseWebhook(body)
 	if err != nil {
+		h.log(r.Context(), slog.LevelWarn, "invalid webhook payload", nil, start, slog.String("error", err.Error()))
+		h.observe(&WebhookEvent{}, start, err)
 		http.Error(w, fmt.Sprintf("Failed to parse webhook: %v", err), http.StatusBadRequest)
 		return
 	}
 	
+	// App events carry tokens and subscription state, so they are only
+	// accepted with a verified signature
+	if h.Secret == "" && strings.HasPrefix(event.Event, "app.") {
+		h.log(r.Context(), slog.LevelWarn, "unsigned app webhook rejected", event, start)
+		http.Error(w, "Signature required", http.StatusUnauthorized)
+		return
+	}
+	
 	// Find and execute the handler for this event type
 	handler, exists := h.Handlers[event.Event]
 	if !exists {
 		// No handler registered for this event type, but still accept it
+		h.log(r.Context(), slog.LevelDebug, "webhook ignored", event, start)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 	
 	// Execute the handler
-	if err := handler(event); err != nil {
+	ctx, span := startSpan(r.Context(), h.Tracer, "salla webhook "+event.Event,
+		slog.String("salla.event", event.Event),
+		slog.Int("salla.merchant", event.Merchant),
+	)
+	event.ctx = ctx
+	err = handler(event)
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+	h.observe(event, start, err)
+	
+	if err != nil {
+		h.log(r.Context(), slog.LevelError, "webhook handler failed", event, start, slog.String("error", err.Error()))
 		http.Error(w, fmt.Sprintf("Handler error: %v", err), http.StatusInternalServerError)
 		return
 	}
 	
+	h.log(r.Context(), slog.LevelInfo, "webhook handled", event, start)
+	if h.Logger != nil && h.Logger.Enabled(r.Context(), slog.LevelDebug) {
+		h.Logger.LogAttrs(r.Context(), slog.LevelDebug, "webhook body",
+			slog.String("event", event.Event),
+			slog.String("body", redactBody(body)),
+		)
+	}
+	
 	w.WriteHeader(http.StatusOK)
 }
 
+// observe reports a dispatched event to the metrics, if set
+func (h *WebhookHandlerFunc) observe(event *WebhookEvent, start time.Time, err error) {
+	if h.Metrics == nil {
+		return
+	}
+	
+	h.Metrics.ObserveWebhook(WebhookMetric{
+		Event:    event.Event,
+		Merchant: event.Merchant,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
+
+// log records the outcome of a delivery if a logger is set
+func (h *WebhookHandlerFunc) log(ctx context.Context, level slog.Level, msg string, event *WebhookEvent, start time.Time, attrs ...slog.Attr) {
+	if h.Logger == nil {
+		return
+	}
+	
+	if event != nil {
+		attrs = append(attrs, slog.String("event", event.Event), slog.Int("merchant", event.Merchant))
+	}
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	h.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
 // Helper functions to convert generic events to typed events
 func convertToProductEvent(event *WebhookEvent) (*ProductWebhookEvent, error) {
 	data, err := json.Marshal(event.Data)
@@ -285,7 +418,7 @@ func convertToCustomerEvent(event *WebhookEvent) (*CustomerWebhookEvent, error)
 	}
 	
 	var customer Customer
-	if err := json.Unmarshal(data, &order); err != nil {
+	if err := json.Unmarshal(data, &customer); err != nil {
 		return nil, err
 	}
 	
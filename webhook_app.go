@@ -0,0 +1,184 @@
+package gosalla
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// App describes the app in app lifecycle webhook events
+type App struct {
+	ID                 int      `json:"id"`
+	AppName            string   `json:"app_name"`
+	AppDescription     string   `json:"app_description,omitempty"`
+	AppType            string   `json:"app_type"`
+	AppScopes          []string `json:"app_scopes,omitempty"`
+	StoreType          string   `json:"store_type"`
+	InstallationDate   string   `json:"installation_date,omitempty"`
+	UninstallationDate string   `json:"uninstallation_date,omitempty"`
+}
+
+// StoreAuthorization is the payload of an app.store.authorize event, sent
+// when a merchant installs an app that uses Salla's easy mode authorization
+type StoreAuthorization struct {
+	App
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+
+	// Expires is the Unix time at which the access token expires
+	Expires int64 `json:"expires"`
+}
+
+// Token returns the authorization as a Token ready to be stored or passed
+// to WithToken
+func (a *StoreAuthorization) Token() *Token {
+	token := &Token{
+		AccessToken:  a.AccessToken,
+		RefreshToken: a.RefreshToken,
+		TokenType:    a.TokenType,
+		Scopes:       parseScopes(a.Scope),
+	}
+	if a.Expires > 0 {
+		token.Expiry = time.Unix(a.Expires, 0)
+	}
+	return token
+}
+
+// AppSettings is the payload of an app.settings.updated event
+type AppSettings struct {
+	ID       int                    `json:"id"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// AppSubscription is the payload of app trial and subscription events
+type AppSubscription struct {
+	ID         int     `json:"id"`
+	AppName    string  `json:"app_name"`
+	AppType    string  `json:"app_type"`
+	PlanType   string  `json:"plan_type"`
+	PlanName   string  `json:"plan_name,omitempty"`
+	PlanPeriod int     `json:"plan_period,omitempty"`
+	StartDate  string  `json:"start_date,omitempty"`
+	EndDate    string  `json:"end_date,omitempty"`
+	Price      float64 `json:"price,omitempty"`
+	Total      float64 `json:"total,omitempty"`
+}
+
+// AppWebhookEvent represents an app.installed, app.uninstalled or
+// app.updated event
+type AppWebhookEvent struct {
+	Event     string    `json:"event"`
+	Merchant  int       `json:"merchant"`
+	Data      App       `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StoreAuthorizeWebhookEvent represents an app.store.authorize event
+type StoreAuthorizeWebhookEvent struct {
+	Event     string             `json:"event"`
+	Merchant  int                `json:"merchant"`
+	Data      StoreAuthorization `json:"data"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// AppSettingsWebhookEvent represents an app.settings.updated event
+type AppSettingsWebhookEvent struct {
+	Event     string      `json:"event"`
+	Merchant  int         `json:"merchant"`
+	Data      AppSettings `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// AppSubscriptionWebhookEvent represents an app trial or subscription event
+type AppSubscriptionWebhookEvent struct {
+	Event     string          `json:"event"`
+	Merchant  int             `json:"merchant"`
+	Data      AppSubscription `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// appSubscriptionEvents are the trial and subscription events
+var appSubscriptionEvents = []string{
+	EventAppTrialStarted,
+	EventAppTrialExpired,
+	EventAppTrialCanceled,
+	EventAppSubscriptionStarted,
+	EventAppSubscriptionExpired,
+	EventAppSubscriptionCanceled,
+	EventAppSubscriptionRenewed,
+}
+
+// OnStoreAuthorize registers a handler for app.store.authorize events that
+// receives the merchant's token, e.g. to save it to a TokenStore. Like all
+// app events, they are rejected unless the handler has a Secret to verify
+// their signature.
+func (h *WebhookHandlerFunc) OnStoreAuthorize(handler func(merchantID int, tok *Token) error) {
+	h.On(EventAppStoreAuthorize, func(event *WebhookEvent) error {
+		var auth StoreAuthorization
+		if err := decodeEventData(event, &auth); err != nil {
+			return err
+		}
+		return handler(event.Merchant, auth.Token())
+	})
+}
+
+// OnAppInstalled registers a handler for app.installed events
+func (h *WebhookHandlerFunc) OnAppInstalled(handler func(*AppWebhookEvent) error) {
+	h.onAppEvent(EventAppInstalled, handler)
+}
+
+// OnAppUninstalled registers a handler for app.uninstalled events
+func (h *WebhookHandlerFunc) OnAppUninstalled(handler func(*AppWebhookEvent) error) {
+	h.onAppEvent(EventAppUninstalled, handler)
+}
+
+// OnAppUpdated registers a handler for app.updated events
+func (h *WebhookHandlerFunc) OnAppUpdated(handler func(*AppWebhookEvent) error) {
+	h.onAppEvent(EventAppUpdated, handler)
+}
+
+// OnAppSettingsUpdated registers a handler for app.settings.updated events
+func (h *WebhookHandlerFunc) OnAppSettingsUpdated(handler func(*AppSettingsWebhookEvent) error) {
+	h.On(EventAppSettingsUpdated, func(event *WebhookEvent) error {
+		settingsEvent := &AppSettingsWebhookEvent{Event: event.Event, Merchant: event.Merchant, CreatedAt: event.CreatedAt}
+		if err := decodeEventData(event, &settingsEvent.Data); err != nil {
+			return err
+		}
+		return handler(settingsEvent)
+	})
+}
+
+// OnAppSubscription registers a handler for every app trial and
+// subscription event. The handler can tell them apart by event.Event.
+func (h *WebhookHandlerFunc) OnAppSubscription(handler func(*AppSubscriptionWebhookEvent) error) {
+	for _, eventType := range appSubscriptionEvents {
+		h.On(eventType, func(event *WebhookEvent) error {
+			subscriptionEvent := &AppSubscriptionWebhookEvent{Event: event.Event, Merchant: event.Merchant, CreatedAt: event.CreatedAt}
+			if err := decodeEventData(event, &subscriptionEvent.Data); err != nil {
+				return err
+			}
+			return handler(subscriptionEvent)
+		})
+	}
+}
+
+// onAppEvent registers handler for an event carrying an App payload
+func (h *WebhookHandlerFunc) onAppEvent(eventType string, handler func(*AppWebhookEvent) error) {
+	h.On(eventType, func(event *WebhookEvent) error {
+		appEvent := &AppWebhookEvent{Event: event.Event, Merchant: event.Merchant, CreatedAt: event.CreatedAt}
+		if err := decodeEventData(event, &appEvent.Data); err != nil {
+			return err
+		}
+		return handler(appEvent)
+	})
+}
+
+// decodeEventData decodes the data of a generic event into v
+func decodeEventData(event *WebhookEvent, v interface{}) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -1,6 +1,15 @@
 package gosalla
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -99,6 +108,133 @@ func TestWebhookHandlerOn(t *testing.T) {
 	}
 }
 
+// signedWebhook creates a webhook request for payload signed with secret
+func signedWebhook(secret, payload string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(payload))
+	req.Header.Set("X-Salla-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestWebhookSignatureHeaders(t *testing.T) {
+	handler := NewWebhookHandler("secret")
+	handler.On(EventProductCreated, func(event *WebhookEvent) error {
+		return nil
+	})
+	payload := `{"event": "product.created", "merchant": 1, "data": {}}`
+	
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedWebhook("secret", payload))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected X-Salla-Signature to be accepted, got %d", rec.Code)
+	}
+	
+	req := signedWebhook("secret", payload)
+	req.Header.Set("X-Signature", req.Header.Get("X-Salla-Signature"))
+	req.Header.Del("X-Salla-Signature")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected X-Signature to be accepted, got %d", rec.Code)
+	}
+	
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedWebhook("other", payload))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong signature to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestOnStoreAuthorize(t *testing.T) {
+	handler := NewWebhookHandler("secret")
+	
+	var merchantID int
+	var token *Token
+	handler.OnStoreAuthorize(func(id int, tok *Token) error {
+		merchantID, token = id, tok
+		return nil
+	})
+	
+	payload := `{
+		"event": "app.store.authorize",
+		"merchant": 1234509876,
+		"created_at": "2022-12-31 12:31:25",
+		"data": {
+			"id": 1911645512,
+			"app_name": "My App",
+			"app_type": "app",
+			"store_type": "development",
+			"access_token": "access",
+			"refresh_token": "refresh",
+			"token_type": "bearer",
+			"scope": "offline_access products.read",
+			"expires": 1700000000
+		}
+	}`
+	
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedWebhook("secret", payload))
+	
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	
+	if merchantID != 1234509876 {
+		t.Errorf("Expected merchant 1234509876, got %d", merchantID)
+	}
+	
+	if token == nil || token.AccessToken != "access" || token.RefreshToken != "refresh" || token.Expiry.Unix() != 1700000000 {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+	
+	if !token.HasScope(ScopeProductsRead) || token.HasScope(ScopeOrdersRead) {
+		t.Errorf("Unexpected scopes: %v", token.Scopes)
+	}
+}
+
+func TestOnStoreAuthorizeRequiresSecret(t *testing.T) {
+	handler := NewWebhookHandler("")
+	
+	called := false
+	handler.OnStoreAuthorize(func(id int, tok *Token) error {
+		called = true
+		return nil
+	})
+	
+	payload := `{"event": "app.store.authorize", "merchant": 1, "data": {"access_token": "planted", "refresh_token": "planted"}}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/webhook", strings.NewReader(payload)))
+	
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unsigned authorize event, got %d", rec.Code)
+	}
+	
+	if called {
+		t.Error("Expected the handler not to receive an unverified token")
+	}
+}
+
+func TestOnAppSubscription(t *testing.T) {
+	handler := NewWebhookHandler("secret")
+	
+	var events []string
+	handler.OnAppSubscription(func(event *AppSubscriptionWebhookEvent) error {
+		events = append(events, event.Event+":"+event.Data.PlanName)
+		return nil
+	})
+	
+	for _, eventType := range []string{EventAppTrialStarted, EventAppSubscriptionRenewed} {
+		payload := `{"event": "` + eventType + `", "merchant": 1, "data": {"id": 1, "plan_type": "recurring", "plan_name": "Pro"}}`
+		handler.ServeHTTP(httptest.NewRecorder(), signedWebhook("secret", payload))
+	}
+	
+	if len(events) != 2 || events[0] != "app.trial.started:Pro" || events[1] != "app.subscription.renewed:Pro" {
+		t.Errorf("Unexpected events: %v", events)
+	}
+}
+
 func TestPagination(t *testing.T) {
 	p := &Pagination{
 		CurrentPage: 2,
@@ -215,3 +351,69 @@ func TestIsRateLimitError(t *testing.T) {
 		t.Error("Expected IsRateLimitError to be false for non-429 error")
 	}
 }
+
+func TestErrorHelpersUnwrap(t *testing.T) {
+	err := fmt.Errorf("failed to get product: %w", &APIError{StatusCode: 404})
+	
+	if !IsNotFoundError(err) || !errors.Is(err, ErrNotFound) {
+		t.Error("Expected wrapped 404 to be a not found error")
+	}
+	
+	if errors.Is(err, ErrRateLimited) || IsUnauthorizedError(err) {
+		t.Error("Expected wrapped 404 not to match other sentinels")
+	}
+}
+
+func TestParseValidationError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Header:     http.Header{"X-Request-Id": []string{"req-1"}},
+		Body: io.NopCloser(strings.NewReader(`{
+			"status": 422,
+			"success": false,
+			"error": {
+				"code": "error",
+				"message": "alert.invalid_fields",
+				"fields": {"name": ["The name field is required."], "price": ["The price must be a number."]}
+			}
+		}`)),
+	}
+	
+	err := fmt.Errorf("failed to create product: %w", parseErrorResponse(resp))
+	
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !IsValidationError(err) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	
+	if got := validationErr.Fields["name"]; len(got) != 1 || got[0] != "The name field is required." {
+		t.Errorf("Unexpected field errors: %v", validationErr.Fields)
+	}
+	
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "error" || apiErr.RequestID != "req-1" || apiErr.Message != "alert.invalid_fields" {
+		t.Errorf("Unexpected API error: %+v", apiErr)
+	}
+	
+	// An empty collection in data must not hide the error details
+	resp = &http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body: io.NopCloser(strings.NewReader(`{"success": false, "data": [], "error": {
+			"code": "error", "message": "alert.invalid_fields", "fields": {"name": ["The name field is required."]}
+		}}`)),
+	}
+	err = parseErrorResponse(resp)
+	if !errors.As(err, &validationErr) || validationErr.Code != "error" || len(validationErr.Fields["name"]) != 1 {
+		t.Errorf("Expected ValidationError with fields, got %v", err)
+	}
+	
+	// A 422 without a JSON body is still a validation error
+	resp = &http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       io.NopCloser(strings.NewReader(`<html>Unprocessable</html>`)),
+	}
+	if err := parseErrorResponse(resp); !IsValidationError(err) {
+		t.Errorf("Expected ValidationError, got %v", err)
+	}
+}
+